@@ -0,0 +1,117 @@
+package logrustash
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+)
+
+var (
+	msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+	cborHandle    = &codec.CborHandle{}
+)
+
+func init() {
+	msgpackHandle.Canonical = true
+	cborHandle.Canonical = true
+}
+
+// MessagePackFormatter formats an entry into a MessagePack map with the same
+// fields as logrus.JSONFormatter.
+//
+// Each entry is a complete MessagePack object and nothing separates entries,
+// so the stream written to a TCP connection can be decoded object by object
+// by the Logstash `msgpack` codec.
+type MessagePackFormatter struct {
+	TimestampFormat  string
+	DisableTimestamp bool
+	FieldMap         logrus.FieldMap
+}
+
+// Format formats an entry into MessagePack.
+func (f *MessagePackFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	return encodeEntry(msgpackHandle, entryMap(e, f.TimestampFormat, f.DisableTimestamp, f.FieldMap))
+}
+
+// CBORFormatter formats an entry into a CBOR map with the same fields
+// as logrus.JSONFormatter.
+//
+// Each entry is a complete CBOR data item and nothing separates entries,
+// so the stream written to a TCP connection is a CBOR sequence (RFC 8742).
+type CBORFormatter struct {
+	TimestampFormat  string
+	DisableTimestamp bool
+	FieldMap         logrus.FieldMap
+}
+
+// Format formats an entry into CBOR.
+func (f *CBORFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	return encodeEntry(cborHandle, entryMap(e, f.TimestampFormat, f.DisableTimestamp, f.FieldMap))
+}
+
+// DefaultMessagePackFormatter returns a Logstash formatter like `DefaultFormatter`
+// which encodes entries into MessagePack.
+func DefaultMessagePackFormatter(fields logrus.Fields, opts ...FormatterOption) logrus.Formatter {
+	return newDefaultFormatter(&MessagePackFormatter{FieldMap: logstashFieldMap}, fields, opts)
+}
+
+// DefaultCBORFormatter returns a Logstash formatter like `DefaultFormatter`
+// which encodes entries into CBOR.
+func DefaultCBORFormatter(fields logrus.Fields, opts ...FormatterOption) logrus.Formatter {
+	return newDefaultFormatter(&CBORFormatter{FieldMap: logstashFieldMap}, fields, opts)
+}
+
+func encodeEntry(h codec.Handle, data map[string]interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := codec.NewEncoder(&b, h).Encode(data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// entryMap returns the fields of the entry `e` the way logrus.JSONFormatter does.
+func entryMap(e *logrus.Entry, timestampFormat string, disableTimestamp bool, fm logrus.FieldMap) map[string]interface{} {
+	data := make(map[string]interface{}, len(e.Data)+3)
+	for k, v := range e.Data {
+		switch v := v.(type) {
+		case error:
+			// Otherwise errors are ignored by the encoders
+			data[k] = v.Error()
+		case time.Time:
+			// Keep the same representation as encoding/json
+			data[k] = v.Format(time.RFC3339Nano)
+		default:
+			data[k] = v
+		}
+	}
+
+	timeKey := resolveKey(fm[logrus.FieldKeyTime], logrus.FieldKeyTime)
+	msgKey := resolveKey(fm[logrus.FieldKeyMsg], logrus.FieldKeyMsg)
+	levelKey := resolveKey(fm[logrus.FieldKeyLevel], logrus.FieldKeyLevel)
+	for _, k := range []string{timeKey, msgKey, levelKey} {
+		if v, ok := data[k]; ok {
+			data["fields."+k] = v
+			delete(data, k)
+		}
+	}
+
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339
+	}
+	if !disableTimestamp {
+		data[timeKey] = e.Time.Format(timestampFormat)
+	}
+	data[msgKey] = e.Message
+	data[levelKey] = e.Level.String()
+	return data
+}
+
+// resolveKey returns the mapped name `v` of a field or its default name `key`.
+func resolveKey(v, key string) string {
+	if v != "" {
+		return v
+	}
+	return key
+}
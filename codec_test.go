@@ -0,0 +1,99 @@
+package logrustash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+)
+
+func TestBinaryFormatters(t *testing.T) {
+	now := time.Date(2017, 8, 25, 14, 38, 22, 0, time.UTC)
+
+	testData := []struct {
+		name      string
+		formatter logrus.Formatter
+		handle    codec.Handle
+	}{
+		{"msgpack", DefaultMessagePackFormatter(logrus.Fields{"type": "app"}), msgpackHandle},
+		{"cbor", DefaultCBORFormatter(logrus.Fields{"type": "app"}), cborHandle},
+	}
+
+	for _, test := range testData {
+		entry := &logrus.Entry{
+			Message: "hello",
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Data:    logrus.Fields{"err": errors.New("boom"), "message": "clash"},
+		}
+
+		res, err := test.formatter.Format(entry)
+		if err != nil {
+			t.Errorf("%s: expected Format to not return error: %s", test.name, err)
+		}
+
+		var data map[string]interface{}
+		if err := codec.NewDecoderBytes(res, test.handle).Decode(&data); err != nil {
+			t.Errorf("%s: expected Decode to not return error: %s", test.name, err)
+		}
+
+		expected := map[string]string{
+			"@timestamp":     "2017-08-25T14:38:22Z",
+			"@version":       "1",
+			"type":           "app",
+			"message":        "hello",
+			"level":          "info",
+			"err":            "boom",
+			"fields.message": "clash",
+		}
+		for k, v := range expected {
+			if data[k] != v {
+				t.Errorf("%s: expected %s to be '%s' but got '%v'", test.name, k, v, data[k])
+			}
+		}
+	}
+}
+
+func TestBinaryFormatterStream(t *testing.T) {
+	f := DefaultMessagePackFormatter(logrus.Fields{})
+
+	var stream bytes.Buffer
+	for _, msg := range []string{"first", "second"} {
+		res, err := f.Format(&logrus.Entry{Message: msg, Data: logrus.Fields{}})
+		if err != nil {
+			t.Errorf("expected Format to not return error: %s", err)
+		}
+		stream.Write(res)
+	}
+
+	dec := codec.NewDecoder(&stream, msgpackHandle)
+	for _, msg := range []string{"first", "second"} {
+		var data map[string]interface{}
+		if err := dec.Decode(&data); err != nil {
+			t.Errorf("expected Decode to not return error: %s", err)
+		}
+		if data["message"] != msg {
+			t.Errorf("expected to see '%s' in '%v'", msg, data["message"])
+		}
+	}
+}
+
+func TestBinaryFormatterEpochMillis(t *testing.T) {
+	f := DefaultCBORFormatter(logrus.Fields{}, FormatEpochMillis())
+
+	res, err := f.Format(&logrus.Entry{Time: time.Unix(1503671902, 123e6), Data: logrus.Fields{}})
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	var data map[string]interface{}
+	if err := codec.NewDecoderBytes(res, cborHandle).Decode(&data); err != nil {
+		t.Errorf("expected Decode to not return error: %s", err)
+	}
+	if ts, ok := data["@timestamp"].(uint64); !ok || ts != 1503671902123 {
+		t.Errorf("expected @timestamp to be epoch millis but got '%#v'", data["@timestamp"])
+	}
+}
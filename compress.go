@@ -0,0 +1,118 @@
+package logrustash
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the algorithm used to compress the data sent to Logstash.
+type Compression int
+
+// Supported compressions.
+const (
+	NoCompression Compression = iota
+	Gzip
+	Zlib
+	Zstd
+)
+
+// flushWriteCloser is a compressing writer which can flush its pending data.
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func newCompressor(w io.Writer, c Compression) (flushWriteCloser, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zlib:
+		return zlib.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression %d", c)
+}
+
+// compressWriter compresses the data written to the underlying writer.
+type compressWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	zw flushWriteCloser
+}
+
+// NewCompressWriter returns a writer which compresses the data written to `w`
+// using the compression `c`. The compressed stream is flushed on each write so
+// that Logstash can decode every entry as soon as it is received.
+//
+// Closing the writer ends the compressed stream and closes `w` if it is an io.Closer.
+func NewCompressWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	zw, err := newCompressor(w, c)
+	if err != nil {
+		return nil, err
+	}
+	return &compressWriter{w: w, zw: zw}, nil
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	n, err := cw.zw.Write(data)
+	if err != nil {
+		return n, err
+	}
+	return n, cw.zw.Flush()
+}
+
+// SetWriteDeadline sets the write deadline of the underlying writer if it supports it.
+func (cw *compressWriter) SetWriteDeadline(t time.Time) error {
+	if d, ok := cw.w.(deadliner); ok {
+		return d.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+func (cw *compressWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	err := cw.zw.Close()
+	if c, ok := cw.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// compressConn is a connection whose writes are compressed.
+// Each pooled connection carries its own compressed stream.
+type compressConn struct {
+	net.Conn
+	cw *compressWriter
+}
+
+func newCompressConn(conn net.Conn, c Compression) (net.Conn, error) {
+	zw, err := newCompressor(conn, c)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &compressConn{Conn: conn, cw: &compressWriter{w: conn, zw: zw}}, nil
+}
+
+func (c *compressConn) Write(data []byte) (int, error) {
+	return c.cw.Write(data)
+}
+
+func (c *compressConn) Close() error {
+	return c.cw.Close()
+}
@@ -0,0 +1,103 @@
+package logrustash
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func decompress(t *testing.T, c Compression, r io.Reader) io.Reader {
+	var zr io.Reader
+	var err error
+	switch c {
+	case Gzip:
+		zr, err = gzip.NewReader(r)
+	case Zlib:
+		zr, err = zlib.NewReader(r)
+	case Zstd:
+		zr, err = zstd.NewReader(r)
+	}
+	if err != nil {
+		t.Fatalf("expected reader to not return error: %s", err)
+	}
+	return zr
+}
+
+func TestCompressWriter(t *testing.T) {
+	for _, c := range []Compression{Gzip, Zlib, Zstd} {
+		buffer := bytes.NewBuffer(nil)
+		w, err := NewCompressWriter(buffer, c)
+		if err != nil {
+			t.Errorf("expected NewCompressWriter to not return error: %s", err)
+			continue
+		}
+
+		data := []byte("{\"message\":\"first\"}\n")
+		if _, err = w.Write(data); err != nil {
+			t.Errorf("expected Write to not return error: %s", err)
+		}
+
+		// the first entry must be readable before the stream is closed
+		got := make([]byte, len(data))
+		if _, err = io.ReadFull(decompress(t, c, bytes.NewReader(buffer.Bytes())), got); err != nil {
+			t.Errorf("expected flushed data to be readable: %s", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("expected to see '%s' in '%s'", data, got)
+		}
+
+		if err = w.Close(); err != nil {
+			t.Errorf("expected Close to not return error: %s", err)
+		}
+	}
+}
+
+func TestCompressWriterError(t *testing.T) {
+	if _, err := NewCompressWriter(bytes.NewBuffer(nil), Compression(42)); err == nil {
+		t.Error("expected NewCompressWriter to return error")
+	}
+}
+
+func TestPoolCompression(t *testing.T) {
+	l, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected Listen to not return error: %s", err)
+	}
+	defer l.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, aerr := l.Accept()
+		if aerr != nil {
+			return
+		}
+		defer conn.Close()
+		zr, zerr := gzip.NewReader(conn)
+		if zerr != nil {
+			return
+		}
+		buf := make([]byte, len("sample data"))
+		if _, rerr := io.ReadFull(zr, buf); rerr == nil {
+			received <- buf
+		}
+	}()
+
+	pool, err := newPool([]string{l.Addr().String()}, 1, 1, PoolCompression(Gzip))
+	if err != nil {
+		t.Fatalf("newPool error: %s", err)
+	}
+	defer pool.Close()
+
+	if _, err = pool.Write([]byte("sample data")); err != nil {
+		t.Errorf("Write error: %s", err)
+	}
+
+	if got := <-received; string(got) != "sample data" {
+		t.Errorf("expected to see '%s' in '%s'", "sample data", got)
+	}
+}
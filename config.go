@@ -0,0 +1,551 @@
+package logrustash
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of a Hook.
+// It can be read from a YAML or JSON file by `ReadConfig`.
+type Config struct {
+	// Transport is one of "tcp" (default), "udp" or "tls".
+	Transport string   `json:"transport" yaml:"transport"`
+	Hosts     []string `json:"hosts" yaml:"hosts"`
+	// TLS configures the "tls" transport; without it, the hosts are
+	// verified with the system roots.
+	TLS *TLSConfig `json:"tls" yaml:"tls"`
+	// Levels are the levels to fire the hook; a single level stands
+	// for itself and all the levels above it. The default is all levels.
+	Levels  []string `json:"levels" yaml:"levels"`
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+	// ConnectTimeout and KeepAlive configure the connections to the hosts,
+	// see `PoolConnectTimeout` and `PoolKeepAlive`.
+	ConnectTimeout Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	KeepAlive      Duration `json:"keep_alive" yaml:"keep_alive"`
+	// Pool is required with several hosts.
+	Pool *PoolConfig `json:"pool" yaml:"pool"`
+	// Async is "", "true" or "buffer", see `Async` and `AsyncBuffer`.
+	Async      string          `json:"async" yaml:"async"`
+	BufferSize uint            `json:"buffer_size" yaml:"buffer_size"`
+	Formatter  FormatterConfig `json:"formatter" yaml:"formatter"`
+	Sampling   *SamplingConfig `json:"sampling" yaml:"sampling"`
+}
+
+// PoolConfig is the configuration of a connection pool.
+type PoolConfig struct {
+	Initial int `json:"initial" yaml:"initial"`
+	Max     int `json:"max" yaml:"max"`
+	// Compression is one of "", "gzip", "zlib" or "zstd".
+	Compression string `json:"compression" yaml:"compression"`
+	// ProbeInterval enables probing the pool, see `PoolProbe`.
+	ProbeInterval Duration `json:"probe_interval" yaml:"probe_interval"`
+	// MaxLifetime and IdleTimeout recycle the connections,
+	// see `PoolMaxLifetime` and `PoolIdleTimeout`.
+	MaxLifetime Duration `json:"max_lifetime" yaml:"max_lifetime"`
+	IdleTimeout Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// DNS, "name:port", or SRV, a SRV record name, resolve the hosts of
+	// the pool every ResolveInterval in place of Hosts, see `PoolResolver`.
+	DNS             string   `json:"dns" yaml:"dns"`
+	SRV             string   `json:"srv" yaml:"srv"`
+	ResolveInterval Duration `json:"resolve_interval" yaml:"resolve_interval"`
+	// Strategy is one of "round_robin" (default), "epsilon_greedy",
+	// "weighted", "least_outstanding", "hash" or "failover", configured by
+	// Epsilon, Weights and HashField, see `PoolStrategy`.
+	Strategy  string         `json:"strategy" yaml:"strategy"`
+	Epsilon   float64        `json:"epsilon" yaml:"epsilon"`
+	Weights   map[string]int `json:"weights" yaml:"weights"`
+	HashField string         `json:"hash_field" yaml:"hash_field"`
+}
+
+// TLSConfig is the configuration of the TLS connections, see `PoolTLSConfig`.
+// CA is the PEM file of the certificates verifying the hosts, Cert and Key
+// the PEM files of the client certificate and ServerName overrides the host
+// name verified.
+type TLSConfig struct {
+	CA         string `json:"ca" yaml:"ca"`
+	Cert       string `json:"cert" yaml:"cert"`
+	Key        string `json:"key" yaml:"key"`
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// FormatterConfig is the configuration of a Logstash formatter.
+type FormatterConfig struct {
+	// Codec is one of "json" (default), "msgpack" or "cbor".
+	Codec  string                 `json:"codec" yaml:"codec"`
+	Fields map[string]interface{} `json:"fields" yaml:"fields"`
+	UTC    bool                   `json:"utc" yaml:"utc"`
+	// Precision is one of "s" (default), "ms", "us" or "ns".
+	Precision    string `json:"precision" yaml:"precision"`
+	EpochMillis  bool   `json:"epoch_millis" yaml:"epoch_millis"`
+	EventCreated bool   `json:"event_created" yaml:"event_created"`
+}
+
+// SamplingConfig is the configuration of a Sampler.
+// Rates are given by level name.
+type SamplingConfig struct {
+	Rates           map[string]float64 `json:"rates" yaml:"rates"`
+	Limit           float64            `json:"limit" yaml:"limit"`
+	Burst           int                `json:"burst" yaml:"burst"`
+	First           int                `json:"first" yaml:"first"`
+	Thereafter      int                `json:"thereafter" yaml:"thereafter"`
+	MaxMessages     int                `json:"max_messages" yaml:"max_messages"`
+	SummaryInterval Duration           `json:"summary_interval" yaml:"summary_interval"`
+}
+
+// Duration is a time.Duration given as a string such as "2s".
+type Duration time.Duration
+
+// UnmarshalJSON reads a duration from a string or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// UnmarshalYAML reads a duration from a string or a number of nanoseconds.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *Duration) set(v interface{}) error {
+	switch t := v.(type) {
+	case string:
+		pd, err := time.ParseDuration(t)
+		if err != nil {
+			return err
+		}
+		*d = Duration(pd)
+		return nil
+	case float64:
+		*d = Duration(t)
+		return nil
+	case int:
+		*d = Duration(t)
+		return nil
+	}
+	return fmt.Errorf("invalid duration %v", v)
+}
+
+// Environment variables overriding the configuration.
+const (
+	envTransport  = "LOGSTASH_TRANSPORT"
+	envHosts      = "LOGSTASH_HOSTS"
+	envLevels     = "LOGSTASH_LEVELS"
+	envTimeout    = "LOGSTASH_TIMEOUT"
+	envPool       = "LOGSTASH_POOL"
+	envAsync      = "LOGSTASH_ASYNC"
+	envBufferSize = "LOGSTASH_BUFFER_SIZE"
+	envType       = "LOGSTASH_TYPE"
+)
+
+// ReadConfig reads the configuration from the YAML (".yaml" or ".yml") or
+// JSON (".json") file `path`, if not empty, then overrides it with the
+// environment variables:
+//
+// LOGSTASH_TRANSPORT: transport;
+// LOGSTASH_HOSTS: comma separated hosts;
+// LOGSTASH_LEVELS: comma separated levels;
+// LOGSTASH_TIMEOUT: timeout, e.g. "2s";
+// LOGSTASH_POOL: initial and max capacities of the pool, e.g. "5,10";
+// LOGSTASH_ASYNC: async mode;
+// LOGSTASH_BUFFER_SIZE: async buffer size;
+// LOGSTASH_TYPE: Logstash "type" field.
+func ReadConfig(path string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &cfg)
+		case ".json":
+			err = json.Unmarshal(data, &cfg)
+		default:
+			err = fmt.Errorf("unsupported configuration file %s", path)
+		}
+		if err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.setEnv(os.Getenv); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// LoadConfig returns a new Hook configured from the file `path` and the
+// environment variables, see `ReadConfig`.
+func LoadConfig(path string) (*Hook, error) {
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(cfg)
+}
+
+// NewFromConfig returns a new Hook configured by `cfg`.
+func NewFromConfig(cfg Config) (*Hook, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithOptions(opts...)
+}
+
+func (c *Config) setEnv(getenv func(string) string) error {
+	if v := getenv(envTransport); v != "" {
+		c.Transport = v
+	}
+	if v := getenv(envHosts); v != "" {
+		c.Hosts = strings.Split(v, ",")
+	}
+	if v := getenv(envLevels); v != "" {
+		c.Levels = strings.Split(v, ",")
+	}
+	if v := getenv(envAsync); v != "" {
+		c.Async = v
+	}
+	if v := getenv(envType); v != "" {
+		if c.Formatter.Fields == nil {
+			c.Formatter.Fields = make(map[string]interface{})
+		}
+		c.Formatter.Fields["type"] = v
+	}
+	if v := getenv(envTimeout); v != "" {
+		if err := c.Timeout.set(v); err != nil {
+			return fmt.Errorf("invalid %s: %s", envTimeout, err)
+		}
+	}
+	if v := getenv(envBufferSize); v != "" {
+		n, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", envBufferSize, err)
+		}
+		c.BufferSize = uint(n)
+	}
+	if v := getenv(envPool); v != "" {
+		if err := c.setPool(v); err != nil {
+			return fmt.Errorf("invalid %s: %s", envPool, err)
+		}
+	}
+	return nil
+}
+
+// setPool sets the pool capacities from a "initial,max" string.
+func (c *Config) setPool(value string) error {
+	caps := strings.Split(value, ",")
+	if len(caps) != 2 {
+		return fmt.Errorf("expected initial,max capacities")
+	}
+	initial, err := strconv.Atoi(caps[0])
+	if err != nil {
+		return err
+	}
+	max, err := strconv.Atoi(caps[1])
+	if err != nil {
+		return err
+	}
+	if c.Pool == nil {
+		c.Pool = &PoolConfig{}
+	}
+	c.Pool.Initial, c.Pool.Max = initial, max
+	return nil
+}
+
+func (c Config) validate() error {
+	switch c.Transport {
+	case "", "tcp", "udp", "tls":
+	default:
+		return fmt.Errorf("unsupported transport %q", c.Transport)
+	}
+	if len(c.Hosts) == 0 && (c.Pool == nil || c.Pool.DNS == "" && c.Pool.SRV == "") {
+		return fmt.Errorf("no hosts")
+	}
+	for _, host := range c.Hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			return err
+		}
+	}
+	if len(c.Hosts) > 1 && c.Pool == nil {
+		return fmt.Errorf("a pool is required for several hosts")
+	}
+	if c.TLS != nil && c.Transport != "tls" {
+		return fmt.Errorf("tls settings require the tls transport")
+	}
+	if c.TLS != nil && (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return fmt.Errorf("a tls certificate requires a key")
+	}
+	if c.Pool != nil && c.Transport == "udp" {
+		return fmt.Errorf("a pool is not supported with udp")
+	}
+	switch c.Async {
+	case "", "false", "true", "buffer":
+	default:
+		return fmt.Errorf("invalid async %q: expected true, false or buffer", c.Async)
+	}
+	if c.BufferSize > 0 && c.Async != "buffer" {
+		return fmt.Errorf("a buffer size requires async buffer")
+	}
+	if c.Pool != nil {
+		switch c.Pool.Compression {
+		case "", "gzip", "zlib", "zstd":
+		default:
+			return fmt.Errorf("unsupported compression %q", c.Pool.Compression)
+		}
+		switch c.Pool.Strategy {
+		case "", "round_robin", "epsilon_greedy", "weighted", "least_outstanding", "failover":
+		case "hash":
+			if c.Pool.HashField == "" {
+				return fmt.Errorf("hash strategy requires a hash field")
+			}
+		default:
+			return fmt.Errorf("unsupported strategy %q", c.Pool.Strategy)
+		}
+		if c.Pool.DNS != "" && c.Pool.SRV != "" {
+			return fmt.Errorf("dns and srv are exclusive")
+		}
+		if c.Pool.DNS != "" {
+			if _, _, err := net.SplitHostPort(c.Pool.DNS); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// options returns the options of a Hook configured by `c`.
+// It connects to the hosts.
+func (c Config) options() ([]Option, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	levels := logrus.AllLevels
+	if len(c.Levels) > 0 {
+		var err error
+		if levels, err = parseLevels(c.Levels); err != nil {
+			return nil, err
+		}
+	}
+	formatter, err := c.Formatter.formatter()
+	if err != nil {
+		return nil, err
+	}
+	opts := []Option{
+		WithFormatter(formatter),
+		WithLevels(levels),
+		WithTimeout(time.Duration(c.Timeout)),
+	}
+	if c.Sampling != nil {
+		s, serr := c.Sampling.sampler()
+		if serr != nil {
+			return nil, serr
+		}
+		opts = append(opts, WithSampler(s))
+	}
+
+	var tlsConfig *tls.Config
+	if c.Transport == "tls" {
+		if tlsConfig, err = c.TLS.config(); err != nil {
+			return nil, err
+		}
+	}
+	if c.Pool != nil {
+		popts := append(c.Pool.options(tlsConfig),
+			PoolConnectTimeout(time.Duration(c.ConnectTimeout)),
+			PoolKeepAlive(time.Duration(c.KeepAlive)))
+		opts = append(opts, WithPool(c.Hosts, c.Pool.Initial, c.Pool.Max, popts...))
+	} else {
+		d := dialer{
+			connectTimeout: time.Duration(c.ConnectTimeout),
+			keepAlive:      time.Duration(c.KeepAlive),
+			tlsConfig:      tlsConfig,
+		}
+		network := c.Transport
+		if network == "" || network == "tls" {
+			network = "tcp"
+		}
+		conn, derr := d.connect(network, c.Hosts[0])
+		if derr != nil {
+			return nil, derr
+		}
+		opts = append(opts, WithWriter(conn))
+	}
+
+	switch c.Async {
+	case "true":
+		opts = append(opts, WithAsync())
+	case "buffer":
+		opts = append(opts, WithAsyncBuffer(c.BufferSize))
+	}
+	return opts, nil
+}
+
+func (c PoolConfig) options(tlsConfig *tls.Config) []PoolOption {
+	var opts []PoolOption
+	if tlsConfig != nil {
+		opts = append(opts, PoolTLSConfig(tlsConfig))
+	}
+	switch c.Compression {
+	case "gzip":
+		opts = append(opts, PoolCompression(Gzip))
+	case "zlib":
+		opts = append(opts, PoolCompression(Zlib))
+	case "zstd":
+		opts = append(opts, PoolCompression(Zstd))
+	}
+	if c.ProbeInterval > 0 {
+		opts = append(opts, PoolProbe(time.Duration(c.ProbeInterval)))
+	}
+	if c.MaxLifetime > 0 {
+		opts = append(opts, PoolMaxLifetime(time.Duration(c.MaxLifetime)))
+	}
+	if c.IdleTimeout > 0 {
+		opts = append(opts, PoolIdleTimeout(time.Duration(c.IdleTimeout)))
+	}
+	if c.DNS != "" {
+		name, port, _ := net.SplitHostPort(c.DNS)
+		opts = append(opts, PoolResolver(DNSResolver(name, port), time.Duration(c.ResolveInterval)))
+	}
+	if c.SRV != "" {
+		opts = append(opts, PoolResolver(SRVResolver(c.SRV), time.Duration(c.ResolveInterval)))
+	}
+	switch c.Strategy {
+	case "epsilon_greedy":
+		opts = append(opts, PoolStrategy(EpsilonGreedy(c.Epsilon)))
+	case "weighted":
+		opts = append(opts, PoolStrategy(Weighted(c.Weights)))
+	case "least_outstanding":
+		opts = append(opts, PoolStrategy(LeastOutstanding()))
+	case "hash":
+		opts = append(opts, PoolStrategy(HashField(c.HashField)))
+	case "failover":
+		opts = append(opts, PoolStrategy(Failover()))
+	}
+	return opts
+}
+
+// config returns the TLS configuration given by `c`, which may be nil.
+func (c *TLSConfig) config() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if c == nil {
+		return cfg, nil
+	}
+	cfg.ServerName = c.ServerName
+	if c.CA != "" {
+		pem, err := ioutil.ReadFile(c.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", c.CA)
+		}
+	}
+	if c.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func (c FormatterConfig) formatter() (logrus.Formatter, error) {
+	var opts []FormatterOption
+	if c.UTC {
+		opts = append(opts, FormatUTC())
+	}
+	if c.EpochMillis {
+		opts = append(opts, FormatEpochMillis())
+	}
+	if c.EventCreated {
+		opts = append(opts, FormatEventCreated())
+	}
+	switch c.Precision {
+	case "", "s":
+	case "ms":
+		opts = append(opts, FormatPrecision(PrecisionMillisecond))
+	case "us":
+		opts = append(opts, FormatPrecision(PrecisionMicrosecond))
+	case "ns":
+		opts = append(opts, FormatPrecision(PrecisionNanosecond))
+	default:
+		return nil, fmt.Errorf("unsupported precision %q", c.Precision)
+	}
+
+	fields := logrus.Fields{}
+	for k, v := range c.Fields {
+		fields[k] = stringKeys(v)
+	}
+	switch c.Codec {
+	case "", "json":
+		return DefaultFormatter(fields, opts...), nil
+	case "msgpack":
+		return DefaultMessagePackFormatter(fields, opts...), nil
+	case "cbor":
+		return DefaultCBORFormatter(fields, opts...), nil
+	}
+	return nil, fmt.Errorf("unsupported codec %q", c.Codec)
+}
+
+func (c SamplingConfig) sampler() (*Sampler, error) {
+	s := &Sampler{
+		Limit:           c.Limit,
+		Burst:           c.Burst,
+		First:           c.First,
+		Thereafter:      c.Thereafter,
+		MaxMessages:     c.MaxMessages,
+		SummaryInterval: time.Duration(c.SummaryInterval),
+	}
+	if len(c.Rates) > 0 {
+		s.Rates = make(map[logrus.Level]float64, len(c.Rates))
+		for name, rate := range c.Rates {
+			l, err := logrus.ParseLevel(name)
+			if err != nil {
+				return nil, err
+			}
+			s.Rates[l] = rate
+		}
+	}
+	return s, nil
+}
+
+// stringKeys converts the maps decoded from YAML, keyed by interface{},
+// to maps keyed by strings which can be encoded by the formatters.
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, mv := range t {
+			m[fmt.Sprint(k)] = stringKeys(mv)
+		}
+		return m
+	case []interface{}:
+		for i, sv := range t {
+			t[i] = stringKeys(sv)
+		}
+	}
+	return v
+}
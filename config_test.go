@@ -0,0 +1,267 @@
+package logrustash
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "logrustash")
+	if err != nil {
+		t.Fatalf("expected TempDir to not return error: %s", err)
+	}
+	path := filepath.Join(dir, name)
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("expected WriteFile to not return error: %s", err)
+	}
+	return path
+}
+
+func TestReadConfig(t *testing.T) {
+	expected := Config{
+		Transport:  "tcp",
+		Hosts:      []string{"ls1:5000", "ls2:5000"},
+		Levels:     []string{"warn"},
+		Timeout:    Duration(2 * time.Second),
+		Pool:       &PoolConfig{Initial: 5, Max: 10, Compression: "gzip"},
+		Async:      "buffer",
+		BufferSize: 100,
+		Formatter: FormatterConfig{
+			Codec:     "msgpack",
+			Fields:    map[string]interface{}{"type": "app"},
+			UTC:       true,
+			Precision: "ms",
+		},
+		Sampling: &SamplingConfig{
+			Rates:           map[string]float64{"debug": 0.5},
+			SummaryInterval: Duration(time.Minute),
+		},
+	}
+
+	testData := map[string]string{
+		"config.yaml": `
+transport: tcp
+hosts: [ls1:5000, ls2:5000]
+levels: [warn]
+timeout: 2s
+pool: {initial: 5, max: 10, compression: gzip}
+async: buffer
+buffer_size: 100
+formatter: {codec: msgpack, fields: {type: app}, utc: true, precision: ms}
+sampling: {rates: {debug: 0.5}, summary_interval: 1m}
+`,
+		"config.json": `{
+"transport": "tcp",
+"hosts": ["ls1:5000", "ls2:5000"],
+"levels": ["warn"],
+"timeout": "2s",
+"pool": {"initial": 5, "max": 10, "compression": "gzip"},
+"async": "buffer",
+"buffer_size": 100,
+"formatter": {"codec": "msgpack", "fields": {"type": "app"}, "utc": true, "precision": "ms"},
+"sampling": {"rates": {"debug": 0.5}, "summary_interval": "1m"}
+}`,
+	}
+
+	for name, content := range testData {
+		path := writeConfig(t, name, content)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		cfg, err := ReadConfig(path)
+		if err != nil {
+			t.Errorf("expected ReadConfig of '%s' to not return error: %s", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("expected to see '%+v' in '%+v'", expected, cfg)
+		}
+	}
+}
+
+func TestReadConfigError(t *testing.T) {
+	testData := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"config.toml", "", "unsupported configuration file"},
+		{"config.json", `{"timeout": "soon"}`, "invalid duration"},
+		{"config.yaml", "hosts: {", "yaml"},
+	}
+
+	for _, test := range testData {
+		path := writeConfig(t, test.name, test.content)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		_, err := ReadConfig(path)
+		if err == nil {
+			t.Errorf("expected ReadConfig of '%s' to return error", test.content)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, err.Error())
+		}
+	}
+}
+
+func TestConfigSetEnv(t *testing.T) {
+	env := map[string]string{
+		"LOGSTASH_TRANSPORT":   "udp",
+		"LOGSTASH_HOSTS":       "ls1:5000,ls2:5000",
+		"LOGSTASH_LEVELS":      "error,info",
+		"LOGSTASH_TIMEOUT":     "3s",
+		"LOGSTASH_POOL":        "1,2",
+		"LOGSTASH_ASYNC":       "true",
+		"LOGSTASH_BUFFER_SIZE": "10",
+		"LOGSTASH_TYPE":        "app",
+	}
+	cfg := Config{Hosts: []string{"ls0:5000"}, Formatter: FormatterConfig{Fields: map[string]interface{}{"env": "dev"}}}
+	if err := cfg.setEnv(func(key string) string { return env[key] }); err != nil {
+		t.Fatalf("expected setEnv to not return error: %s", err)
+	}
+
+	expected := Config{
+		Transport:  "udp",
+		Hosts:      []string{"ls1:5000", "ls2:5000"},
+		Levels:     []string{"error", "info"},
+		Timeout:    Duration(3 * time.Second),
+		Pool:       &PoolConfig{Initial: 1, Max: 2},
+		Async:      "true",
+		BufferSize: 10,
+		Formatter:  FormatterConfig{Fields: map[string]interface{}{"env": "dev", "type": "app"}},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected to see '%+v' in '%+v'", expected, cfg)
+	}
+
+	for key, value := range map[string]string{"LOGSTASH_TIMEOUT": "3", "LOGSTASH_POOL": "1", "LOGSTASH_BUFFER_SIZE": "-1"} {
+		err := (&Config{}).setEnv(func(k string) string {
+			if k == key {
+				return value
+			}
+			return ""
+		})
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("expected to see '%s' in '%v'", key, err)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	testData := []struct {
+		cfg      Config
+		expected string
+	}{
+		{Config{}, "no hosts"},
+		{Config{Transport: "http", Hosts: []string{"ls1:5000"}}, "unsupported transport"},
+		{Config{Hosts: []string{"ls1"}}, "missing port"},
+		{Config{Hosts: []string{"ls1:5000", "ls2:5000"}}, "a pool is required"},
+		{Config{Transport: "udp", Hosts: []string{"ls1:5000"}, Pool: &PoolConfig{}}, "not supported with udp"},
+		{Config{Hosts: []string{"ls1:5000"}, Async: "maybe"}, "invalid async"},
+		{Config{Hosts: []string{"ls1:5000"}, BufferSize: 10}, "requires async buffer"},
+		{Config{Hosts: []string{"ls1:5000"}, Pool: &PoolConfig{Compression: "lz4"}}, "unsupported compression"},
+		{Config{Pool: &PoolConfig{DNS: "logstash"}}, "missing port"},
+		{Config{Hosts: []string{"ls1:5000"}, Pool: &PoolConfig{Strategy: "random"}}, "unsupported strategy"},
+		{Config{Hosts: []string{"ls1:5000"}, Pool: &PoolConfig{Strategy: "hash"}}, "hash field"},
+		{Config{Pool: &PoolConfig{DNS: "logstash:5000", SRV: "_logstash._tcp.logstash"}}, "exclusive"},
+		{Config{Hosts: []string{"ls1:5000"}, TLS: &TLSConfig{CA: "ca.pem"}}, "require the tls transport"},
+		{Config{Transport: "tls", Hosts: []string{"ls1:5000"}, TLS: &TLSConfig{Cert: "cert.pem"}}, "requires a key"},
+	}
+
+	for _, test := range testData {
+		err := test.cfg.validate()
+		if err == nil {
+			t.Errorf("expected validate of '%+v' to return error", test.cfg)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, err.Error())
+		}
+	}
+}
+
+func TestNewFromConfigError(t *testing.T) {
+	testData := []struct {
+		cfg      Config
+		expected string
+	}{
+		{Config{Hosts: []string{address}, Levels: []string{"loud"}}, "not a valid logrus Level"},
+		{Config{Hosts: []string{address}, Formatter: FormatterConfig{Codec: "xml"}}, "unsupported codec"},
+		{Config{Hosts: []string{address}, Formatter: FormatterConfig{Precision: "ps"}}, "unsupported precision"},
+		{Config{Hosts: []string{address}, Sampling: &SamplingConfig{Rates: map[string]float64{"loud": 1}}}, "not a valid logrus Level"},
+		{Config{Transport: "tls", Hosts: []string{address}, TLS: &TLSConfig{CA: "missing.pem"}}, "no such file"},
+	}
+
+	for _, test := range testData {
+		_, err := NewFromConfig(test.cfg)
+		if err == nil {
+			t.Errorf("expected NewFromConfig of '%+v' to return error", test.cfg)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, err.Error())
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, "config.yml", `
+hosts: [`+address+`]
+levels: [info]
+pool: {initial: 1, max: 2}
+async: buffer
+formatter: {fields: {type: app}}
+sampling: {first: 10}
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	h, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("expected LoadConfig to not return error: %s", err)
+	}
+
+	if len(h.Levels()) != 5 || h.buf == nil || h.sampler == nil || h.sampler.First != 10 {
+		t.Errorf("expected hook to be configured: %#v", h)
+	}
+	f, ok := h.formatter.(LogstashFormatter)
+	if !ok || f.Fields["type"] != "app" {
+		t.Errorf("expected formatter with type field but got %#v", h.formatter)
+	}
+	if h.Levels()[4] != logrus.InfoLevel {
+		t.Errorf("expected to see '%v' in '%v'", logrus.InfoLevel, h.Levels())
+	}
+}
+
+func TestNewFromConfigTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	ca := writeConfig(t, "ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})))
+	cfg := Config{Transport: "tls", Hosts: []string{srv.Listener.Addr().String()}, TLS: &TLSConfig{CA: ca}}
+
+	h, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("expected NewFromConfig to not return error: %s", err)
+	}
+	conn, ok := h.writer.(*tls.Conn)
+	if !ok {
+		t.Fatalf("expected a TLS connection but got %T", h.writer)
+	}
+	_ = conn.Close()
+
+	cfg.TLS = nil
+	if _, err = NewFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("expected the system roots to not verify the host: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package logrustash
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields added to the entry reporting duplicates.
+const (
+	repeatCountKey = "repeat_count"
+	firstSeenKey   = "first_seen"
+	lastSeenKey    = "last_seen"
+)
+
+// defaultMaxEntries is the number of entries tracked by a Deduplicator by default.
+const defaultMaxEntries = 10000
+
+// Deduplicator collapses identical entries within a time window.
+// Entries are identical when they have the same level, message and values
+// of the fields named in Fields.
+//
+// The first entry is sent right away. The identical entries received during
+// the following Window are not sent; when the window ends, one entry is sent
+// in their place, a copy of the first one with "repeat_count" set to their
+// number and "first_seen" and "last_seen" to the time of the first and last of them.
+//
+// MaxEntries is the number of distinct entries tracked at once, 10000 by
+// default; beyond it, the new entries are sent untracked until a window ends.
+type Deduplicator struct {
+	Window     time.Duration
+	Fields     []string
+	MaxEntries int
+
+	mu      sync.Mutex
+	pending map[string]*duplicates
+}
+
+type duplicates struct {
+	entry     *logrus.Entry
+	count     uint64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+func (d *Deduplicator) key(e *logrus.Entry) string {
+	parts := make([]string, 0, len(d.Fields)+2)
+	parts = append(parts, e.Level.String(), e.Message)
+	for _, f := range d.Fields {
+		parts = append(parts, fmt.Sprint(e.Data[f]))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// dedup reports whether the entry `e` is sent. When the window of a duplicated
+// entry ends, the entry reporting the duplicates is passed to `emit`.
+func (d *Deduplicator) dedup(e *logrus.Entry, now time.Time, emit func(*logrus.Entry)) bool {
+	if d.Window <= 0 {
+		return true
+	}
+
+	key := d.key(e)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if dup, ok := d.pending[key]; ok {
+		if dup.count == 0 {
+			dup.firstSeen = now
+		}
+		dup.count++
+		dup.lastSeen = now
+		return false
+	}
+
+	max := d.MaxEntries
+	if max <= 0 {
+		max = defaultMaxEntries
+	}
+	if len(d.pending) >= max {
+		return true
+	}
+	if d.pending == nil {
+		d.pending = make(map[string]*duplicates)
+	}
+	d.pending[key] = &duplicates{entry: dupEntry(e)}
+	time.AfterFunc(d.Window, func() {
+		if report := d.expire(key); report != nil {
+			emit(report)
+		}
+	})
+	return true
+}
+
+// expire ends the window of the entry `key` and returns the entry reporting
+// its duplicates or nil if there were none.
+func (d *Deduplicator) expire(key string) *logrus.Entry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dup := d.pending[key]
+	delete(d.pending, key)
+	if dup == nil || dup.count == 0 {
+		return nil
+	}
+
+	report := dup.entry
+	report.Time = dup.lastSeen
+	report.Data[repeatCountKey] = dup.count
+	report.Data[firstSeenKey] = dup.firstSeen
+	report.Data[lastSeenKey] = dup.lastSeen
+	return report
+}
+
+// dupEntry returns a copy of the entry `e` and its data.
+func dupEntry(e *logrus.Entry) *logrus.Entry {
+	data := make(logrus.Fields, len(e.Data)+3)
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	return &logrus.Entry{
+		Logger:  e.Logger,
+		Data:    data,
+		Time:    e.Time,
+		Level:   e.Level,
+		Message: e.Message,
+	}
+}
@@ -0,0 +1,102 @@
+package logrustash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := &Deduplicator{Window: 50 * time.Millisecond, Fields: []string{"dep"}}
+	reports := make(chan *logrus.Entry, 2)
+	emit := func(e *logrus.Entry) {
+		reports <- e
+	}
+
+	entry := &logrus.Entry{Message: "connection failed", Level: logrus.ErrorLevel, Data: logrus.Fields{"dep": "db", "attempt": 1}}
+	other := &logrus.Entry{Message: "connection failed", Level: logrus.ErrorLevel, Data: logrus.Fields{"dep": "cache"}}
+
+	now := time.Now()
+	if !d.dedup(entry, now, emit) {
+		t.Error("expected first entry to be sent")
+	}
+	if !d.dedup(other, now, emit) {
+		t.Error("expected entry with other field values to be sent")
+	}
+	for i := 1; i <= 3; i++ {
+		if d.dedup(entry, now.Add(time.Duration(i)*time.Millisecond), emit) {
+			t.Error("expected duplicate entry to not be sent")
+		}
+	}
+
+	select {
+	case report := <-reports:
+		if report.Message != "connection failed" || report.Data["dep"] != "db" {
+			t.Errorf("expected report of the duplicated entry but got %#v", report)
+		}
+		if report.Data[repeatCountKey] != uint64(3) {
+			t.Errorf("expected repeat_count to be 3 but got %v", report.Data[repeatCountKey])
+		}
+		if report.Data[firstSeenKey] != now.Add(time.Millisecond) || report.Data[lastSeenKey] != now.Add(3*time.Millisecond) {
+			t.Errorf("expected first and last seen times but got %v and %v", report.Data[firstSeenKey], report.Data[lastSeenKey])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a report of the duplicates")
+	}
+
+	if _, ok := entry.Data[repeatCountKey]; ok {
+		t.Errorf("expected entry to not be changed: %#v", entry.Data)
+	}
+
+	// the window of an entry without duplicates ends without report
+	select {
+	case report := <-reports:
+		t.Errorf("expected no report but got %#v", report)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if !d.dedup(entry, time.Now(), emit) {
+		t.Error("expected entry to be sent after the window")
+	}
+}
+
+func TestDeduplicatorMaxEntries(t *testing.T) {
+	d := &Deduplicator{Window: time.Minute, MaxEntries: 2}
+	emit := func(*logrus.Entry) {}
+	now := time.Now()
+
+	for _, msg := range []string{"first", "second", "third", "third"} {
+		if !d.dedup(&logrus.Entry{Message: msg}, now, emit) {
+			t.Errorf("expected the entry %q to be sent", msg)
+		}
+	}
+	if len(d.pending) != 2 {
+		t.Errorf("expected 2 entries to be tracked but got %d", len(d.pending))
+	}
+	if d.dedup(&logrus.Entry{Message: "first"}, now, emit) {
+		t.Error("expected the duplicate of a tracked entry to not be sent")
+	}
+}
+
+func TestFireWithDeduplicator(t *testing.T) {
+	buffer := &syncBuffer{}
+	h := New(buffer, simpleFmter{})
+	h.SetDeduplicator(&Deduplicator{Window: 20 * time.Millisecond})
+
+	for i := 0; i < 5; i++ {
+		if err := h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}}); err != nil {
+			t.Errorf("expected Fire to not return error: %s", err)
+		}
+	}
+
+	expected := "msg: \"failed\""
+	if buffer.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, buffer.String())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if buffer.String() != expected+expected {
+		t.Errorf("expected to see '%s' in '%s'", expected+expected, buffer.String())
+	}
+}
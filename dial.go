@@ -0,0 +1,104 @@
+package logrustash
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// defaultConnectTimeout is the duration of time a connection to a host may take.
+const defaultConnectTimeout = 3 * time.Second
+
+// DialFunc connects to `address` on `network` until `ctx` is done, such as
+// the DialContext method of net.Dialer or of a proxy dialer.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// PoolDialContext sets the function used to connect to the hosts of the pool,
+// in place of a net.Dialer with the keep-alive period of `PoolKeepAlive`.
+func PoolDialContext(dial DialFunc) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.dialer.dial = dial
+	}
+}
+
+// PoolConnectTimeout sets the duration of time a connection to a host may
+// take. The default is 3 seconds.
+func PoolConnectTimeout(d time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.dialer.connectTimeout = d
+	}
+}
+
+// PoolKeepAlive sets the period of the TCP keep-alives of the connections
+// to the hosts; keep-alives are disabled if `d` is negative. The default is
+// the default of net.Dialer.
+func PoolKeepAlive(d time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.dialer.keepAlive = d
+	}
+}
+
+// PoolTLSConfig connects to the hosts of the pool with TLS configured by
+// `cfg`, such as the root CAs verifying the hosts and the client certificates.
+// If the ServerName of `cfg` is empty, the host name of each host is used.
+// Without it, the "tls" transport verifies the hosts with the system roots.
+func PoolTLSConfig(cfg *tls.Config) PoolOption {
+	return func(pc *poolConfig) {
+		pc.dialer.tlsConfig = cfg
+	}
+}
+
+// dialer connects to the hosts of a Hook.
+// The connections use TLS if tlsConfig is not nil.
+type dialer struct {
+	dial           DialFunc
+	connectTimeout time.Duration
+	keepAlive      time.Duration
+	tlsConfig      *tls.Config
+}
+
+// connect connects to `address` on `network` within the connect timeout,
+// and performs the TLS handshake if the dialer uses TLS.
+func (d dialer) connect(network, address string) (net.Conn, error) {
+	timeout := d.connectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dial := d.dial
+	if dial == nil {
+		nd := &net.Dialer{KeepAlive: d.keepAlive}
+		dial = nd.DialContext
+	}
+	conn, err := dial(ctx, network, address)
+	if err != nil || d.tlsConfig == nil {
+		return conn, err
+	}
+	return handshake(ctx, conn, address, d.tlsConfig)
+}
+
+// handshake returns the TLS client connection configured by `cfg` over `conn`
+// to `address` once the handshake completed before `ctx` is done.
+func handshake(ctx context.Context, conn net.Conn, address string, cfg *tls.Config) (net.Conn, error) {
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		cfg.ServerName = host
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
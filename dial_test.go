@@ -0,0 +1,100 @@
+package logrustash
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolDialContext(t *testing.T) {
+	l := listen(t, false)
+	defer l.Close()
+
+	var dialed []string
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the dial context to have a deadline")
+		}
+		dialed = append(dialed, network+" "+address)
+		var d net.Dialer
+		return d.DialContext(ctx, network, l.Addr().String())
+	}
+
+	p, err := newPool([]string{"ls1:5000"}, 1, 1, PoolDialContext(dial))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	if len(dialed) != 1 || dialed[0] != "tcp ls1:5000" {
+		t.Errorf("expected to see '%v' in '%v'", []string{"tcp ls1:5000"}, dialed)
+	}
+	if _, err = p.Write([]byte("data")); err != nil {
+		t.Errorf("expected Write to not return error: %s", err)
+	}
+}
+
+func TestPoolConnectTimeout(t *testing.T) {
+	block := func(ctx context.Context, network, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	_, err := newPool([]string{"ls1:5000"}, 1, 1, PoolDialContext(block), PoolConnectTimeout(20*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected newPool to return error")
+	}
+	if !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("expected to see '%s' in '%s'", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the connection to time out after 20ms but took %s", elapsed)
+	}
+}
+
+func TestDialerTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	d := dialer{tlsConfig: &tls.Config{}}
+	if _, err := d.connect("tcp", srv.Listener.Addr().String()); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("expected the handshake to fail on the certificate: %v", err)
+	}
+
+	d.tlsConfig = nil
+	conn, err := d.connect("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("expected connect to not return error: %s", err)
+	}
+	_ = conn.Close()
+}
+
+func TestPoolTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	cfg := &tls.Config{RootCAs: roots}
+
+	p, err := newPool([]string{srv.Listener.Addr().String()}, 1, 1, PoolTLSConfig(cfg))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+	if cfg.ServerName != "" {
+		t.Errorf("expected the configuration to not be modified: %q", cfg.ServerName)
+	}
+
+	cfg.ServerName = "logstash.invalid"
+	if _, err = newPool([]string{srv.Listener.Addr().String()}, 1, 1, PoolTLSConfig(cfg)); err == nil {
+		t.Error("expected newPool to fail on the server name")
+	}
+}
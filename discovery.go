@@ -0,0 +1,123 @@
+package logrustash
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// resolveTimeout is the duration of time a resolution of the hosts may take.
+const resolveTimeout = 10 * time.Second
+
+// Resolver returns the addresses, "host:port", of the hosts of a pool.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// ResolverFunc is a function used as a Resolver.
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+// Resolve implements Resolver.
+func (f ResolverFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// lookuper is implemented by *net.Resolver.
+type lookuper interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+type dnsResolver struct {
+	lookup lookuper
+	name   string
+	port   string
+}
+
+// DNSResolver returns a resolver of the A and AAAA records of `name`, the
+// hosts listening on `port`, such as a Kubernetes headless service.
+func DNSResolver(name, port string) Resolver {
+	return dnsResolver{lookup: net.DefaultResolver, name: name, port: port}
+}
+
+func (r dnsResolver) Resolve(ctx context.Context) ([]string, error) {
+	addrs, err := r.lookup.LookupHost(ctx, r.name)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = net.JoinHostPort(addr, r.port)
+	}
+	return hosts, nil
+}
+
+type srvResolver struct {
+	lookup lookuper
+	name   string
+}
+
+// SRVResolver returns a resolver of the SRV records of `name`, such as
+// "_logstash._tcp.logstash.default.svc.cluster.local".
+func SRVResolver(name string) Resolver {
+	return srvResolver{lookup: net.DefaultResolver, name: name}
+}
+
+func (r srvResolver) Resolve(ctx context.Context) ([]string, error) {
+	_, srvs, err := r.lookup.LookupSRV(ctx, "", "", r.name)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(srvs))
+	for i, srv := range srvs {
+		hosts[i] = net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+	}
+	return hosts, nil
+}
+
+// PoolResolver sets the hosts of the pool to the hosts returned by `r`, in
+// place of the hosts given to the pool, and resolves them again every
+// `interval`: the new hosts are added to the pool and the connections to the
+// hosts which are gone are closed instead of being used. A failed or empty
+// resolution keeps the hosts of the pool.
+func PoolResolver(r Resolver, interval time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.resolver = r
+		cfg.resolveInterval = interval
+	}
+}
+
+// resolve resolves the hosts of the pool with `r`, updates the pool and
+// returns the hosts removed.
+func (s *hostSet) resolve(r Resolver) ([]*poolHost, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	addrs, err := r.Resolve(ctx)
+	if err != nil || len(addrs) == 0 {
+		return nil, err
+	}
+	return s.update(addrs), nil
+}
+
+// discover resolves the hosts of the pool every `interval` until the pool is closed.
+func (p *logstashPool) discover(r Resolver, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			removed, err := p.hosts.resolve(r)
+			if err != nil {
+				logrus.Warnf("Error during resolving logstash hosts: %v\n", err)
+			}
+			p.conns.drop(removed...)
+		}
+	}
+}
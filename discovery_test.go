@@ -0,0 +1,114 @@
+package logrustash
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLookup struct {
+	addrs []string
+	srvs  []*net.SRV
+	err   error
+}
+
+func (f fakeLookup) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return f.addrs, f.err
+}
+
+func (f fakeLookup) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return name, f.srvs, f.err
+}
+
+func TestDNSResolver(t *testing.T) {
+	r := dnsResolver{lookup: fakeLookup{addrs: []string{"10.0.0.1", "::1"}}, name: "logstash", port: "5000"}
+
+	hosts, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("expected Resolve to not return error: %s", err)
+	}
+	expected := []string{"10.0.0.1:5000", "[::1]:5000"}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, hosts)
+	}
+
+	r.lookup = fakeLookup{err: errors.New("no such host")}
+	if _, err = r.Resolve(context.Background()); err == nil {
+		t.Error("expected Resolve to return error")
+	}
+}
+
+func TestSRVResolver(t *testing.T) {
+	r := srvResolver{lookup: fakeLookup{srvs: []*net.SRV{
+		{Target: "ls1.logstash.svc.", Port: 5044},
+		{Target: "ls2.logstash.svc.", Port: 5045},
+	}}, name: "_logstash._tcp.logstash.svc"}
+
+	hosts, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("expected Resolve to not return error: %s", err)
+	}
+	expected := []string{"ls1.logstash.svc:5044", "ls2.logstash.svc:5045"}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, hosts)
+	}
+}
+
+func TestPoolResolver(t *testing.T) {
+	first := listen(t, false)
+	defer first.Close()
+	second := listen(t, false)
+	defer second.Close()
+
+	var mu sync.Mutex
+	addrs := []string{first.Addr().String()}
+	r := ResolverFunc(func(ctx context.Context) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return addrs, nil
+	})
+
+	p, err := newPool(nil, 1, 2, PoolResolver(r, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	if hosts := p.stats(); len(hosts) != 1 || hosts[0].Host != first.Addr().String() || hosts[0].Connections != 1 {
+		t.Fatalf("expected one connection to the first host but got %+v", hosts)
+	}
+
+	mu.Lock()
+	addrs = []string{second.Addr().String()}
+	mu.Unlock()
+
+	for i := 0; i < 50 && p.hosts.list()[0].addr != second.Addr().String(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hosts := p.hosts.list()
+	if len(hosts) != 1 || hosts[0].addr != second.Addr().String() {
+		t.Fatalf("expected the second host but got %v", hosts)
+	}
+
+	conn, err := p.conns.Get()
+	if err != nil {
+		t.Fatalf("expected get to not return error: %s", err)
+	}
+	defer conn.Close()
+	if hc := hostConnOf(conn); hc.host.addr != second.Addr().String() {
+		t.Errorf("expected a connection to the second host but got %s", hc.host.addr)
+	}
+}
+
+func TestPoolResolverError(t *testing.T) {
+	r := ResolverFunc(func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("no such host")
+	})
+	if _, err := newPool(nil, 1, 2, PoolResolver(r, time.Second)); err == nil {
+		t.Error("expected newPool to return error")
+	}
+}
@@ -0,0 +1,193 @@
+package logrustash
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrWriterClosed is returned when writing to a closed writer.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// Destination is a writer of a FanoutWriter.
+// BufSize is the number of writes buffered for the destination (default 8192),
+// Retries the number of times a failed write is retried and Timeout the
+// duration of time before a write timesout.
+type Destination struct {
+	Name    string
+	Writer  io.Writer
+	BufSize uint
+	Retries int
+	Timeout time.Duration
+}
+
+// PoolDestination returns a destination writing to a new connection pool for `hosts`.
+func PoolDestination(name string, hosts []string, initialCap, maxCap int, opts ...PoolOption) (Destination, error) {
+	p, err := newPool(hosts, initialCap, maxCap, opts...)
+	if err != nil {
+		return Destination{}, err
+	}
+	return Destination{Name: name, Writer: p}, nil
+}
+
+// DestinationStats holds the delivery counters of a destination.
+type DestinationStats struct {
+	Name     string
+	Written  uint64
+	Errors   uint64
+	Retries  uint64
+	Dropped  uint64
+	Buffered int
+}
+
+type destination struct {
+	// counters are first to be 64-bit aligned for atomic operations
+	written uint64
+	errors  uint64
+	retries uint64
+	dropped uint64
+
+	Destination
+	buf  chan []byte
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// FanoutWriter writes the data to several destinations.
+// Each destination has its own buffer and background process, so a slow
+// or failing destination does not delay the others nor the caller.
+// To initialize it use the `NewFanoutWriter` function.
+type FanoutWriter struct {
+	dests  []*destination
+	closed bool
+	mu     sync.RWMutex
+}
+
+// NewFanoutWriter returns a writer sending all data to every destination.
+//
+// To ship logs to two Logstash clusters:
+//
+// old := logrustash.Destination{Name: "old", Writer: oldConn}
+// next := logrustash.Destination{Name: "new", Writer: newConn, Retries: 3}
+// w := logrustash.NewFanoutWriter(old, next)
+// hook := logrustash.New(w, logrustash.DefaultFormatter(logrus.Fields{}))
+func NewFanoutWriter(dests ...Destination) *FanoutWriter {
+	fw := &FanoutWriter{}
+	for _, d := range dests {
+		bsize := d.BufSize
+		if bsize <= 0 {
+			bsize = defaultBufSize
+		}
+		dest := &destination{
+			Destination: d,
+			buf:         make(chan []byte, bsize),
+			done:        make(chan struct{}),
+		}
+		fw.dests = append(fw.dests, dest)
+		go dest.process()
+	}
+	return fw
+}
+
+// Write queues a copy of `data` for every destination and returns immediately.
+// If the buffer of a destination is full, the data is dropped for that destination.
+func (fw *FanoutWriter) Write(data []byte) (int, error) {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+
+	if fw.closed {
+		return 0, ErrWriterClosed
+	}
+
+	d := make([]byte, len(data))
+	copy(d, data)
+	for _, dest := range fw.dests {
+		dest.wg.Add(1)
+		select {
+		case dest.buf <- d:
+		default:
+			atomic.AddUint64(&dest.dropped, 1)
+			dest.wg.Done()
+		}
+	}
+	return len(data), nil
+}
+
+// Flush waits for the buffers of all destinations to be empty.
+// The writes are blocked meanwhile.
+func (fw *FanoutWriter) Flush() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	for _, dest := range fw.dests {
+		dest.wg.Wait()
+	}
+}
+
+// Close sends the buffered data, stops the background processes
+// and closes the destination writers which are io.Closer.
+func (fw *FanoutWriter) Close() error {
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return nil
+	}
+	fw.closed = true
+	fw.mu.Unlock()
+
+	var err error
+	for _, dest := range fw.dests {
+		close(dest.buf)
+		<-dest.done
+		if c, ok := dest.Writer.(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// Stats returns the delivery counters of every destination.
+func (fw *FanoutWriter) Stats() []DestinationStats {
+	stats := make([]DestinationStats, 0, len(fw.dests))
+	for _, dest := range fw.dests {
+		stats = append(stats, DestinationStats{
+			Name:     dest.Name,
+			Written:  atomic.LoadUint64(&dest.written),
+			Errors:   atomic.LoadUint64(&dest.errors),
+			Retries:  atomic.LoadUint64(&dest.retries),
+			Dropped:  atomic.LoadUint64(&dest.dropped),
+			Buffered: len(dest.buf),
+		})
+	}
+	return stats
+}
+
+func (d *destination) process() {
+	defer close(d.done)
+	for data := range d.buf {
+		if err := d.write(data); err != nil {
+			logrus.Warnf("Error during sending message to logstash %s: %v\n", d.Name, err)
+		}
+		d.wg.Done()
+	}
+}
+
+func (d *destination) write(data []byte) error {
+	err := write(d.Writer, d.Timeout, data)
+	for retriesLeft := d.Retries; err != nil && retriesLeft > 0; retriesLeft-- {
+		atomic.AddUint64(&d.retries, 1)
+		err = write(d.Writer, d.Timeout, data)
+	}
+	if err != nil {
+		atomic.AddUint64(&d.errors, 1)
+		return err
+	}
+	atomic.AddUint64(&d.written, 1)
+	return nil
+}
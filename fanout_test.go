@@ -0,0 +1,139 @@
+package logrustash
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(data []byte) (int, error) {
+	<-w.release
+	return len(data), nil
+}
+
+func TestFanoutWriter(t *testing.T) {
+	first := &syncBuffer{}
+	second := &syncBuffer{}
+	fw := NewFanoutWriter(
+		Destination{Name: "first", Writer: first},
+		Destination{Name: "second", Writer: second},
+	)
+
+	data := []byte("sample data")
+	n, err := fw.Write(data)
+	if err != nil {
+		t.Errorf("expected Write to not return error: %s", err)
+	}
+	if n != len(data) {
+		t.Errorf("expected to see '%d' in '%d'", len(data), n)
+	}
+	data[0] = 'S' // the writer must keep its own copy
+
+	fw.Flush()
+
+	for _, buf := range []*syncBuffer{first, second} {
+		if buf.String() != "sample data" {
+			t.Errorf("expected to see '%s' in '%s'", "sample data", buf.String())
+		}
+	}
+
+	if err = fw.Close(); err != nil {
+		t.Errorf("expected Close to not return error: %s", err)
+	}
+	if _, err = fw.Write(data); err != ErrWriterClosed {
+		t.Errorf("expected Write to return '%v' but got '%v'", ErrWriterClosed, err)
+	}
+}
+
+func TestFanoutWriterIndependentDestinations(t *testing.T) {
+	fast := &syncBuffer{}
+	slow := blockingWriter{release: make(chan struct{})}
+	fw := NewFanoutWriter(
+		Destination{Name: "slow", Writer: slow, BufSize: 1},
+		Destination{Name: "fast", Writer: fast},
+		Destination{Name: "broken", Writer: failWrite{}, Retries: 2},
+	)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			_, _ = fw.Write([]byte("x"))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Write to not be blocked by a slow destination")
+	}
+
+	close(slow.release)
+	fw.Flush()
+
+	if fast.String() != "xxxxx" {
+		t.Errorf("expected to see '%s' in '%s'", "xxxxx", fast.String())
+	}
+
+	stats := fw.Stats()
+	if stats[0].Dropped == 0 || stats[0].Written+stats[0].Dropped != 5 {
+		t.Errorf("expected slow destination to drop entries: %+v", stats[0])
+	}
+	if stats[1].Written != 5 || stats[1].Dropped != 0 {
+		t.Errorf("expected fast destination to write all entries: %+v", stats[1])
+	}
+	if stats[2].Errors != 5 || stats[2].Retries != 10 {
+		t.Errorf("expected broken destination to retry and fail: %+v", stats[2])
+	}
+}
+
+func TestFanoutWriterConcurrentFlush(t *testing.T) {
+	buf := &syncBuffer{}
+	fw := NewFanoutWriter(Destination{Name: "buf", Writer: buf})
+	defer fw.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = fw.Write([]byte("x"))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fw.Flush()
+			}
+		}()
+	}
+	wg.Wait()
+	fw.Flush()
+
+	if n := len(buf.String()); n != 400 {
+		t.Errorf("expected 400 writes but got %d", n)
+	}
+}
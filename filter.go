@@ -0,0 +1,60 @@
+package logrustash
+
+import (
+	"reflect"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoggerKey is the field holding the name of the logger.
+const LoggerKey = "logger"
+
+// Filter reports whether an entry is sent to Logstash.
+type Filter func(*logrus.Entry) bool
+
+// Keep returns a filter sending only the entries matching `match`.
+func Keep(match func(*logrus.Entry) bool) Filter {
+	return Filter(match)
+}
+
+// Drop returns a filter not sending the entries matching `match`.
+//
+// To not send the health check requests to Logstash:
+//
+// hook.AddFilter(logrustash.Drop(logrustash.FieldEquals("path", "/healthz")))
+func Drop(match func(*logrus.Entry) bool) Filter {
+	return func(e *logrus.Entry) bool {
+		return !match(e)
+	}
+}
+
+// FieldEquals matches the entries with the field `key` set to `value`.
+func FieldEquals(key string, value interface{}) func(*logrus.Entry) bool {
+	return func(e *logrus.Entry) bool {
+		v, ok := e.Data[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// MessageMatches matches the entries with a message matching `re`.
+func MessageMatches(re *regexp.Regexp) func(*logrus.Entry) bool {
+	return func(e *logrus.Entry) bool {
+		return re.MatchString(e.Message)
+	}
+}
+
+// LoggerNamed matches the entries with the "logger" field set to `name`.
+func LoggerNamed(name string) func(*logrus.Entry) bool {
+	return FieldEquals(LoggerKey, name)
+}
+
+// keep reports whether the entry passes all `filters`.
+func keep(filters []Filter, e *logrus.Entry) bool {
+	for _, f := range filters {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package logrustash
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFilters(t *testing.T) {
+	entry := &logrus.Entry{
+		Message: "GET /healthz",
+		Data:    logrus.Fields{"path": "/healthz", LoggerKey: "http"},
+	}
+
+	testData := []struct {
+		name     string
+		filter   Filter
+		expected bool
+	}{
+		{"drop field", Drop(FieldEquals("path", "/healthz")), false},
+		{"drop other field", Drop(FieldEquals("path", "/users")), true},
+		{"keep message", Keep(MessageMatches(regexp.MustCompile(`^GET `))), true},
+		{"drop message", Drop(MessageMatches(regexp.MustCompile(`health`))), false},
+		{"keep logger", Keep(LoggerNamed("db")), false},
+		{"drop logger", Drop(LoggerNamed("db")), true},
+		{"custom", func(e *logrus.Entry) bool { return len(e.Data) > 1 }, true},
+	}
+
+	for _, test := range testData {
+		if got := test.filter(entry); got != test.expected {
+			t.Errorf("%s: expected %t but got %t", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestFireWithFilters(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	h := New(buffer, simpleFmter{})
+	h.AddFilter(Drop(FieldEquals("path", "/healthz")))
+
+	entries := []*logrus.Entry{
+		{Message: "health", Data: logrus.Fields{"path": "/healthz"}},
+		{Message: "users", Data: logrus.Fields{"path": "/users"}},
+	}
+	for _, entry := range entries {
+		if err := h.Fire(entry); err != nil {
+			t.Errorf("expected Fire to not return error: %s", err)
+		}
+	}
+
+	expected := "msg: \"users\""
+	if buffer.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, buffer.String())
+	}
+}
@@ -39,11 +39,37 @@ func releaseEntry(e *logrus.Entry) {
 // LogstashFormatter represents a Logstash format.
 // It has logrus.Formatter which formats the entry and logrus.Fields which
 // are added to the JSON message if not given in the entry data.
+// The "tags" and "@metadata" fields are merged with the entry data instead.
+//
+// Schema optionally declares the type of fields; values are coerced to
+// the declared types before formatting. When StrictSchema is set, values
+// that cannot be coerced are moved to "_invalid.<name>".
+//
+// The Max* limits, when greater than zero, truncate the message, the string
+// field values (strings, byte slices, errors and Stringers), the number of
+// fields and the size of the formatted entry; the other values are never
+// truncated, so an entry may still exceed MaxSize. Affected fields are listed
+// in the "_truncated" field, counted in MaxFields. The "@version", "type", "tags" and "@metadata" fields are
+// never truncated nor dropped.
+//
+// UTC, Precision, EpochMillis and EventCreated control the timestamps.
 //
 // Note: use the `DefaultFormatter` function to set a default Logstash formatter.
 type LogstashFormatter struct {
 	logrus.Formatter
 	logrus.Fields
+	Schema       Schema
+	StrictSchema bool
+
+	MaxMessageLength int
+	MaxFieldLength   int
+	MaxFields        int
+	MaxSize          int
+
+	UTC          bool
+	Precision    Precision
+	EpochMillis  bool
+	EventCreated bool
 }
 
 var (
@@ -59,18 +85,28 @@ var (
 // "type" to "log" (unless set differently in `fields`),
 // "@timestamp" to the log time and "message" to the log message.
 //
+// The timestamps can be configured using `opts`.
+//
 // Note: to set a different configuration use the `LogstashFormatter` structure.
-func DefaultFormatter(fields logrus.Fields) logrus.Formatter {
+func DefaultFormatter(fields logrus.Fields, opts ...FormatterOption) logrus.Formatter {
+	return newDefaultFormatter(&logrus.JSONFormatter{FieldMap: logstashFieldMap}, fields, opts)
+}
+
+func newDefaultFormatter(fmter logrus.Formatter, fields logrus.Fields, opts []FormatterOption) logrus.Formatter {
 	for k, v := range logstashFields {
 		if _, ok := fields[k]; !ok {
 			fields[k] = v
 		}
 	}
 
-	return LogstashFormatter{
-		Formatter: &logrus.JSONFormatter{FieldMap: logstashFieldMap},
+	f := LogstashFormatter{
+		Formatter: fmter,
 		Fields:    fields,
 	}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f
 }
 
 // Format formats an entry to a Logstash format according to the given Formatter and Fields.
@@ -78,7 +114,16 @@ func DefaultFormatter(fields logrus.Fields) logrus.Formatter {
 // Note: the given entry is copied and not changed during the formatting process.
 func (f LogstashFormatter) Format(e *logrus.Entry) ([]byte, error) {
 	ne := copyEntry(e, f.Fields)
-	dataBytes, err := f.Formatter.Format(ne)
-	releaseEntry(ne)
+	defer releaseEntry(ne)
+
+	mergeLogstashFields(ne, f.Fields, e.Data)
+	f.Schema.apply(ne.Data, f.StrictSchema)
+	truncated := f.limit(ne)
+	fmter := f.timeFormatter(ne)
+
+	dataBytes, err := fmter.Format(ne)
+	if err == nil && f.MaxSize > 0 && len(dataBytes) > f.MaxSize {
+		return f.shrink(fmter, ne, dataBytes, truncated)
+	}
 	return dataBytes, err
 }
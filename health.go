@@ -0,0 +1,96 @@
+package logrustash
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBufferThreshold = 0.9
+	defaultUnhealthyAfter  = 30 * time.Second
+)
+
+// Errors returned by `Healthy`.
+var (
+	ErrNoHostReachable = errors.New("no logstash host reachable")
+	ErrBufferFull      = errors.New("async buffer above threshold")
+	ErrWriteFailed     = errors.New("writes failing")
+)
+
+// SetBufferThreshold sets the ratio of the capacity of the async buffer
+// above which the Hook is not healthy, see `Healthy`. The default is 0.9.
+func (h *Hook) SetBufferThreshold(ratio float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.threshold = ratio
+}
+
+// SetUnhealthyAfter sets the duration of time the writes fail, without any
+// successful write, before the Hook is not healthy, see `Healthy`.
+// The default is 30 seconds.
+func (h *Hook) SetUnhealthyAfter(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.unhealthy = d
+}
+
+// Healthy returns nil if the Hook can currently deliver entries, otherwise:
+//
+// ErrNoHostReachable if the last connection or write to every host of the pool failed;
+// ErrBufferFull if the async buffer is filled above the threshold;
+// ErrWriteFailed if the writes failed for longer than `SetUnhealthyAfter`.
+//
+// The write failures of a pool are forgotten once a probe restores one of its hosts.
+func (h *Hook) Healthy() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	failedAt := atomic.LoadInt64(&h.stats.failedAt)
+	if p, ok := h.writer.(*logstashPool); ok {
+		if !p.reachable() {
+			return ErrNoHostReachable
+		}
+		if failedAt != 0 && atomic.LoadInt64(&p.restoredAt) >= failedAt {
+			atomic.CompareAndSwapInt64(&h.stats.failedAt, failedAt, 0)
+			failedAt = 0
+		}
+	}
+	if h.buf != nil {
+		threshold := h.threshold
+		if threshold <= 0 {
+			threshold = defaultBufferThreshold
+		}
+		if float64(len(h.buf)) >= threshold*float64(cap(h.buf)) {
+			return ErrBufferFull
+		}
+	}
+	if failedAt != 0 {
+		unhealthy := h.unhealthy
+		if unhealthy <= 0 {
+			unhealthy = defaultUnhealthyAfter
+		}
+		if time.Since(time.Unix(0, failedAt)) >= unhealthy {
+			return ErrWriteFailed
+		}
+	}
+	return nil
+}
+
+// HealthHandler returns a http.Handler reporting the health of `h`, see `Healthy`.
+// It responds "ok" with status 200 if the Hook is healthy, otherwise the
+// error with status 503, to be used as a readiness probe.
+func HealthHandler(h *Hook) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := h.Healthy(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+}
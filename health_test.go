@@ -0,0 +1,122 @@
+package logrustash
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHealthy(t *testing.T) {
+	h := New(bytes.NewBuffer(nil), simpleFmter{})
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy: %s", err)
+	}
+
+	h.writer = failWrite{}
+	_ = h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}})
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy after a single failure: %s", err)
+	}
+
+	// the writes have been failing for a minute
+	h.stats.failedAt = time.Now().Add(-time.Minute).UnixNano()
+	_ = h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}})
+	if err := h.Healthy(); err != ErrWriteFailed {
+		t.Errorf("expected to see '%v' in '%v'", ErrWriteFailed, err)
+	}
+
+	h.writer = bytes.NewBuffer(nil)
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy again: %s", err)
+	}
+}
+
+func TestHealthyBuffer(t *testing.T) {
+	release := make(chan struct{})
+	h := New(blockingWriter{release: release}, simpleFmter{})
+	h.AsyncBuffer(10)
+	h.SetBufferThreshold(0.5)
+
+	for i := 0; i < 7; i++ {
+		_ = h.Fire(&logrus.Entry{Message: "buffered", Data: logrus.Fields{}})
+	}
+	if err := h.Healthy(); err != ErrBufferFull {
+		t.Errorf("expected to see '%v' in '%v'", ErrBufferFull, err)
+	}
+
+	close(release)
+	h.Flush()
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy: %s", err)
+	}
+}
+
+func TestHealthyPool(t *testing.T) {
+	h := New(nil, simpleFmter{})
+	if err := h.UsePool([]string{"127.0.0.1:1"}, 0, 1); err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy before any connection: %s", err)
+	}
+
+	_ = h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}})
+	if err := h.Healthy(); err != ErrNoHostReachable {
+		t.Errorf("expected to see '%v' in '%v'", ErrNoHostReachable, err)
+	}
+	if s := h.Stats(); s.Hosts[0].Up {
+		t.Errorf("expected host to be down: %+v", s.Hosts[0])
+	}
+}
+
+func TestHealthyPoolRestored(t *testing.T) {
+	l := listen(t, false)
+	defer l.Close()
+
+	h := New(nil, simpleFmter{})
+	h.SetUnhealthyAfter(time.Nanosecond)
+	if err := h.UsePool([]string{l.Addr().String()}, 1, 1); err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+	p := h.writer.(*logstashPool)
+	defer p.Close()
+
+	h.stats.failedAt = time.Now().UnixNano()
+	time.Sleep(time.Millisecond)
+	if err := h.Healthy(); err != ErrWriteFailed {
+		t.Errorf("expected to see '%v' in '%v'", ErrWriteFailed, err)
+	}
+
+	p.hosts.list()[0].setDown(errors.New("down"))
+	p.probeHosts()
+	if err := h.Healthy(); err != nil {
+		t.Errorf("expected hook to be healthy once the host is restored: %s", err)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	h := New(bytes.NewBuffer(nil), simpleFmter{})
+	h.SetUnhealthyAfter(time.Nanosecond)
+	handler := HealthHandler(h)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("expected 200 ok but got %d %s", rec.Code, rec.Body.String())
+	}
+
+	h.writer = failWrite{}
+	_ = h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}})
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != ErrWriteFailed.Error() {
+		t.Errorf("expected 503 %s but got %d %s", ErrWriteFailed, rec.Code, rec.Body.String())
+	}
+}
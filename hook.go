@@ -1,9 +1,10 @@
 package logrustash
 
 import (
+	"errors"
 	"io"
-	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -11,15 +12,44 @@ import (
 
 const defaultBufSize uint = 8192
 
+// ErrNoPool is returned when managing the hosts of a Hook without a pool.
+var ErrNoPool = errors.New("hook does not use a pool")
+
+// deadliner is implemented by writers supporting a write timeout, such as net.Conn.
+type deadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// keyer is implemented by writers selecting where to write an entry from its fields.
+type keyer interface {
+	keyed(entry *logrus.Entry) io.Writer
+}
+
+// writerOf returns the writer of `entry` to `w`.
+func writerOf(w io.Writer, entry *logrus.Entry) io.Writer {
+	if k, ok := w.(keyer); ok {
+		return k.keyed(entry)
+	}
+	return w
+}
+
 // Hook represents a logrus hook for Logstash.
-// To initialize it use the `New` function.
+// To initialize it use the `New` or `NewWithOptions` functions.
 type Hook struct {
+	stats     hookStats // first to be 64-bit aligned for atomic operations
 	writer    io.Writer
 	formatter logrus.Formatter
 	levels    []logrus.Level
+	filters   []Filter
+	sampler   *Sampler
+	dedup     *Deduplicator
+	observer  Observer
+	observers []*Observer // copied on write, see `AddObserver`
 	timeout   time.Duration
 	async     bool
-	buf       chan *logrus.Entry
+	buf       chan delivery
+	threshold float64
+	unhealthy time.Duration
 	wg        sync.WaitGroup
 	mu        sync.RWMutex
 }
@@ -42,104 +72,325 @@ func New(w io.Writer, f logrus.Formatter) *Hook {
 // Fire takes, formats and sends the entry to Logstash.
 // Hook's formatter is used to format the entry into Logstash format
 // and Hook's writer is used to write the formatted entry to the Logstash instance.
+// The entries with a level not in the levels of the Hook are ignored, since
+// logrus only reads the levels when the Hook is added.
 func (h *Hook) Fire(entry *logrus.Entry) error {
 	h.mu.RLock() // Claim the mutex as a RLock - allowing multiple go routines to log simultaneously
 	defer h.mu.RUnlock()
 
+	if !hasLevel(h.levels, entry.Level) {
+		return nil
+	}
+
+	atomic.AddUint64(&h.stats.fired, 1)
+	if !keep(h.filters, entry) {
+		atomic.AddUint64(&h.stats.filtered, 1)
+		return nil
+	}
+
+	now := time.Now()
+	if h.dedup != nil && !h.dedup.dedup(entry, now, h.fireReport) {
+		atomic.AddUint64(&h.stats.duplicated, 1)
+		return nil
+	}
+
+	if h.sampler != nil && !h.sampler.sample(entry, now, h.fireReport) {
+		atomic.AddUint64(&h.stats.sampled, 1)
+		return nil
+	}
+
+	return h.send(entry)
+}
+
+// fireReport sends an entry generated by the Hook, such as a duplicates report.
+func (h *Hook) fireReport(entry *logrus.Entry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if err := h.send(entry); err != nil {
+		logrus.Warnf("Error during sending message to logstash: %v\n", err)
+	}
+}
+
+// send sends the entry synchronously or asynchronously depending on the Hook mode.
+func (h *Hook) send(entry *logrus.Entry) error {
+	d := delivery{
+		entry:     entry,
+		formatter: h.formatter,
+		writer:    h.writer,
+		timeout:   h.timeout,
+		stats:     &h.stats,
+		observer:  h.observer,
+		observers: h.observers,
+		fired:     time.Now(),
+	}
 	if !h.async {
-		return h.fire(entry)
+		return d.send()
 	}
 
 	// send log asynchroniously and return no error.
+	h.wg.Add(1)
 
 	// if a buffering is enabled push the entry to the buffer
 	// and process using a background process
 	if h.buf != nil {
-		h.wg.Add(1)
-		h.buf <- entry
+		h.buf <- d
+		h.stats.buffered(len(h.buf))
 	} else {
 		// otherwise no buffer so just process the request in a background process
-		go h.fire(entry)
+		go func() {
+			_ = d.send()
+			h.wg.Done()
+		}()
 	}
 	return nil
 }
 
-// Levels returns all logrus levels.
+// Levels returns the levels to fire this hook.
 func (h *Hook) Levels() []logrus.Level {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	return h.levels
 }
 
 // SetLevels sets logging level to fire this hook.
+// It takes effect on a Hook already added to a logger, see `Fire`.
 func (h *Hook) SetLevels(levels []logrus.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	h.levels = levels
 }
 
+// AddFilter adds filters deciding which entries are sent to Logstash.
+// An entry is sent only if it passes all filters.
+func (h *Hook) AddFilter(filters ...Filter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.filters = append(h.filters, filters...)
+}
+
+// SetSampler sets the sampler deciding which entries are sent to Logstash.
+func (h *Hook) SetSampler(s *Sampler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sampler = s
+}
+
+// SetDeduplicator sets the deduplicator collapsing identical entries.
+// Duplicates are removed before the entries are buffered.
+func (h *Hook) SetDeduplicator(d *Deduplicator) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dedup = d
+}
+
+// SetObserver sets the function observing each delivery, see `Observer`.
+func (h *Hook) SetObserver(o Observer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.observer = o
+}
+
+// AddObserver adds a function observing each delivery in addition to the
+// one set by `SetObserver`, such as the exporters of the metrics packages.
+// The returned function removes it.
+func (h *Hook) AddObserver(o Observer) (remove func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	added := &o
+	h.observers = append(h.observers[:len(h.observers):len(h.observers)], added)
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		observers := make([]*Observer, 0, len(h.observers))
+		for _, obs := range h.observers {
+			if obs != added {
+				observers = append(observers, obs)
+			}
+		}
+		h.observers = observers
+	}
+}
+
 // SetTimeout sets the duration of time before writing a message timesout.
 func (h *Hook) SetTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	h.timeout = d
 }
 
 // UsePool creates a connection pool for logstash to enable support for handling
 // connection failures, use of multiple logstash instances within a cluster.
-func (h *Hook) UsePool(hosts []string, initialCap, maxCap int) error {
-	p, err := newPool(hosts, initialCap, maxCap)
+// The pool can be configured using `opts`.
+func (h *Hook) UsePool(hosts []string, initialCap, maxCap int, opts ...PoolOption) error {
+	p, err := newPool(hosts, initialCap, maxCap, opts...)
 	if err != nil {
 		return err
 	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	h.writer = p
 	return nil
 }
 
+// AddHost adds the host `addr`, "host:port", to the pool of the Hook.
+// It returns ErrNoPool if the Hook does not use a pool, see `UsePool`.
+func (h *Hook) AddHost(addr string) error {
+	p, err := h.pool()
+	if err != nil {
+		return err
+	}
+	return p.AddHost(addr)
+}
+
+// RemoveHost removes the host `addr` from the pool of the Hook: the entries
+// being written to it are delivered before its connections are closed.
+// It returns ErrNoPool if the Hook does not use a pool, see `UsePool`.
+func (h *Hook) RemoveHost(addr string) error {
+	p, err := h.pool()
+	if err != nil {
+		return err
+	}
+	return p.RemoveHost(addr)
+}
+
+// Hosts returns the addresses of the hosts of the pool of the Hook,
+// or nil if the Hook does not use a pool.
+func (h *Hook) Hosts() []string {
+	p, err := h.pool()
+	if err != nil {
+		return nil
+	}
+	return p.Hosts()
+}
+
+func (h *Hook) pool() (*logstashPool, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	p, ok := h.writer.(*logstashPool)
+	if !ok {
+		return nil, ErrNoPool
+	}
+	return p, nil
+}
+
 // Async sets async flag and send log asynchroniously.
 // If use this option, Fire() does not return error.
 func (h *Hook) Async() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	h.async = true
 }
 
 // AsyncBuffer creates a buffer for log entries and starts a
 // background process to handle processing the buffer entries.
+// It does nothing if the Hook already has a buffer.
 func (h *Hook) AsyncBuffer(bufsize uint) {
-	bsize := bufsize
-	if bsize <= 0 {
-		bsize = defaultBufSize
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.startBuffer(bufsize)
+}
+
+func (h *Hook) startBuffer(bufsize uint) {
+	if h.buf != nil {
+		return
 	}
 
-	h.Async()
-	h.buf = make(chan *logrus.Entry, bsize)
-	go h.processBuffer() // Log in background
+	h.async = true
+	h.buf = make(chan delivery, bufSize(bufsize))
+	go h.processBuffer(h.buf) // Log in background
+}
+
+// bufSize returns the size of an async buffer of `bufsize`, the default size if 0.
+func bufSize(bufsize uint) uint {
+	if bufsize == 0 {
+		return defaultBufSize
+	}
+	return bufsize
 }
 
 // Flush waits for the log queue to be empty.
 func (h *Hook) Flush() {
-	if !h.async || h.buf == nil {
-		return
-	}
 	h.mu.Lock() // claim the mutex as a Lock - we want exclusive access to it
 	defer h.mu.Unlock()
 
 	h.wg.Wait()
 }
 
-func (h *Hook) processBuffer() {
-	for {
-		entry := <-h.buf // receive new entry on channel
-		if err := h.fire(entry); err != nil {
+func (h *Hook) processBuffer(buf chan delivery) {
+	for d := range buf { // receive new entry on channel
+		if err := d.send(); err != nil {
 			logrus.Warnf("Error during sending message to logstash: %v\n", err)
 		}
 		h.wg.Done()
 	}
 }
 
-func (h *Hook) fire(entry *logrus.Entry) error {
-	dataBytes, err := h.formatter.Format(entry)
+// delivery is an entry to send with the formatter, writer and timeout
+// of the Hook at the time the entry was fired.
+type delivery struct {
+	entry     *logrus.Entry
+	formatter logrus.Formatter
+	writer    io.Writer
+	timeout   time.Duration
+	stats     *hookStats
+	observer  Observer
+	observers []*Observer
+	fired     time.Time
+}
+
+func (d delivery) send() error {
+	err := d.deliver()
+	latency := time.Since(d.fired)
+	if d.observer != nil {
+		d.observer(latency, err)
+	}
+	for _, o := range d.observers {
+		(*o)(latency, err)
+	}
+	return err
+}
+
+func (d delivery) deliver() error {
+	dataBytes, err := d.formatter.Format(d.entry)
 	if err != nil {
+		atomic.AddUint64(&d.stats.formatErrors, 1)
 		return err
 	}
-	if h.timeout > 0 {
-		if conn, ok := h.writer.(net.Conn); ok {
-			_ = conn.SetWriteDeadline(time.Now().Add(h.timeout))
+	atomic.AddUint64(&d.stats.formatted, 1)
+
+	if err = write(writerOf(d.writer, d.entry), d.timeout, dataBytes); err != nil {
+		atomic.AddUint64(&d.stats.writeErrors, 1)
+		atomic.CompareAndSwapInt64(&d.stats.failedAt, 0, time.Now().UnixNano())
+		return err
+	}
+	atomic.AddUint64(&d.stats.written, 1)
+	atomic.StoreInt64(&d.stats.failedAt, 0)
+	atomic.AddUint64(&d.stats.bytesSent, uint64(len(dataBytes)))
+	return nil
+}
+
+// write writes `data` to `w` within `timeout` if `w` supports write deadlines.
+func write(w io.Writer, timeout time.Duration, data []byte) error {
+	if timeout > 0 {
+		if conn, ok := w.(deadliner); ok {
+			_ = conn.SetWriteDeadline(time.Now().Add(timeout))
 		}
 	}
-	_, err = h.writer.Write(dataBytes)
+	_, err := w.Write(data)
 	return err
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"testing"
 	"time"
@@ -45,6 +46,22 @@ func (f failFmt) Format(e *logrus.Entry) ([]byte, error) {
 	return nil, errors.New("")
 }
 
+func TestSetLevels(t *testing.T) {
+	buffer := &syncBuffer{}
+	h := New(buffer, simpleFmter{})
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	log.Hooks.Add(h)
+
+	h.SetLevels([]logrus.Level{logrus.ErrorLevel})
+	log.Info("info")
+	log.Error("error")
+
+	if expected := "msg: \"error\""; buffer.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, buffer.String())
+	}
+}
+
 func TestFireFormatError(t *testing.T) {
 	buffer := bytes.NewBuffer(nil)
 	h := New(buffer, failFmt{})
@@ -157,6 +174,34 @@ func TestUsePoolError(t *testing.T) {
 	}
 }
 
+func TestHookHosts(t *testing.T) {
+	h := New(nil, simpleFmter{})
+	if err := h.AddHost(address); err != ErrNoPool {
+		t.Errorf("expected to see '%v' in '%v'", ErrNoPool, err)
+	}
+	if err := h.RemoveHost(address); err != ErrNoPool {
+		t.Errorf("expected to see '%v' in '%v'", ErrNoPool, err)
+	}
+	if hosts := h.Hosts(); hosts != nil {
+		t.Errorf("expected no hosts but got %v", hosts)
+	}
+
+	l := listen(t, false)
+	defer l.Close()
+	if err := h.UsePool([]string{l.Addr().String()}, 1, 1); err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+	if err := h.AddHost(address); err != nil {
+		t.Errorf("expected AddHost to not return error: %s", err)
+	}
+	if err := h.RemoveHost(l.Addr().String()); err != nil {
+		t.Errorf("expected RemoveHost to not return error: %s", err)
+	}
+	if hosts := h.Hosts(); len(hosts) != 1 || hosts[0] != address {
+		t.Errorf("expected to see '%v' in '%v'", []string{address}, hosts)
+	}
+}
+
 func TestSetTimeout_Ignored(t *testing.T) {
 	buffer := bytes.NewBuffer(nil)
 	h := New(buffer, simpleFmter{})
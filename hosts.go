@@ -0,0 +1,278 @@
+package logrustash
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/fatih/pool.v2"
+)
+
+// latencyDecay is the inverse of the weight of each write in the moving
+// average of the write latency of a host.
+const latencyDecay = 8
+
+// A host is not selected for hostRetryDelay after a connection or a write to
+// it failed, unless it is restored by probing. The delay doubles each time the
+// host fails again once retried, up to hostMaxRetryDelay, as in go-hostpool.
+const (
+	hostRetryDelay    = 30 * time.Second
+	hostMaxRetryDelay = 900 * time.Second
+)
+
+// poolHost is a host of a pool with its counters.
+type poolHost struct {
+	// counters are first to be 64-bit aligned for atomic operations
+	dials       uint64
+	dialErrors  uint64
+	written     uint64
+	bytesSent   uint64
+	errors      uint64
+	open        int64
+	outstanding int64 // writes in progress
+	latency     int64 // moving average of the write latency in nanoseconds, 0 before the first write
+	failedAt    int64 // unix nanoseconds of the last failure, 0 if the last attempt succeeded
+	retryDelay  int64 // nanoseconds the host is not selected after failedAt
+	removed     int32
+
+	addr string
+	mu   sync.Mutex // serializes the failures
+}
+
+// setDown records whether the last connection or write to the host failed.
+// The failures before the host is retried do not change its retry delay.
+func (h *poolHost) setDown(err error) {
+	if err == nil {
+		atomic.StoreInt64(&h.failedAt, 0)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	delay := hostRetryDelay
+	if failedAt := atomic.LoadInt64(&h.failedAt); failedAt != 0 {
+		delay = time.Duration(atomic.LoadInt64(&h.retryDelay))
+		if now.Sub(time.Unix(0, failedAt)) < delay {
+			return
+		}
+		if delay *= 2; delay > hostMaxRetryDelay {
+			delay = hostMaxRetryDelay
+		}
+	}
+	atomic.StoreInt64(&h.retryDelay, int64(delay))
+	atomic.StoreInt64(&h.failedAt, now.UnixNano())
+}
+
+// up reports whether the last connection or write to the host succeeded.
+func (h *poolHost) up() bool {
+	return atomic.LoadInt64(&h.failedAt) == 0
+}
+
+// available reports whether the host can be selected at `now`.
+func (h *poolHost) available(now time.Time) bool {
+	failedAt := atomic.LoadInt64(&h.failedAt)
+	return failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= time.Duration(atomic.LoadInt64(&h.retryDelay))
+}
+
+// observe adds the latency `d` of a write to the moving average of the host.
+func (h *poolHost) observe(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&h.latency)
+		avg := int64(d)
+		if old > 0 {
+			avg = old + (avg-old)/latencyDecay
+		}
+		if atomic.CompareAndSwapInt64(&h.latency, old, avg) {
+			return
+		}
+	}
+}
+
+func (h *poolHost) stats() HostStats {
+	return HostStats{
+		Host:        h.addr,
+		Dials:       atomic.LoadUint64(&h.dials),
+		DialErrors:  atomic.LoadUint64(&h.dialErrors),
+		Written:     atomic.LoadUint64(&h.written),
+		BytesSent:   atomic.LoadUint64(&h.bytesSent),
+		Errors:      atomic.LoadUint64(&h.errors),
+		Connections: int(atomic.LoadInt64(&h.open)),
+		Up:          h.up(),
+	}
+}
+
+// hostSet selects the hosts of a pool with a Strategy, skipping the hosts
+// which are down.
+type hostSet struct {
+	mu       sync.Mutex
+	hosts    []*poolHost
+	strategy Strategy
+}
+
+func newHostSet(addrs []string) *hostSet {
+	s := &hostSet{strategy: RoundRobin()}
+	for _, addr := range addrs {
+		s.hosts = append(s.hosts, &poolHost{addr: addr})
+	}
+	return s
+}
+
+// pick returns the host selected by the strategy for `key` among the
+// available hosts or, if all hosts are down, among all hosts.
+// It returns nil if there are no hosts.
+func (s *hostSet) pick(key string) *poolHost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.hosts) == 0 {
+		return nil
+	}
+	now := time.Now()
+	hosts := make([]*poolHost, 0, len(s.hosts))
+	for _, h := range s.hosts {
+		if h.available(now) {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = s.hosts
+	}
+	return s.strategy.pick(hosts, key)
+}
+
+// update sets the hosts to `addrs`: the new hosts are added after the
+// others and the hosts not in `addrs` are removed and returned.
+func (s *hostSet) update(addrs []string) []*poolHost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keep := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = true
+	}
+
+	var removed []*poolHost
+	hosts := make([]*poolHost, 0, len(addrs))
+	for _, h := range s.hosts {
+		if keep[h.addr] {
+			hosts = append(hosts, h)
+			delete(keep, h.addr)
+		} else {
+			atomic.StoreInt32(&h.removed, 1)
+			removed = append(removed, h)
+		}
+	}
+	for _, addr := range addrs {
+		if keep[addr] {
+			hosts = append(hosts, &poolHost{addr: addr})
+			delete(keep, addr)
+		}
+	}
+	s.hosts = hosts
+	return removed
+}
+
+// add adds the host `addr` after the others, if it is not in the set.
+func (s *hostSet) add(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range s.hosts {
+		if h.addr == addr {
+			return
+		}
+	}
+	s.hosts = append(s.hosts, &poolHost{addr: addr})
+}
+
+// remove removes and returns the host `addr`, or nil if it is not in the set.
+func (s *hostSet) remove(addr string) *poolHost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, h := range s.hosts {
+		if h.addr == addr {
+			atomic.StoreInt32(&h.removed, 1)
+			s.hosts = append(s.hosts[:i:i], s.hosts[i+1:]...)
+			return h
+		}
+	}
+	return nil
+}
+
+// drained reports whether the host was removed from its set.
+func (h *poolHost) drained() bool {
+	return atomic.LoadInt32(&h.removed) != 0
+}
+
+// list returns the hosts.
+func (s *hostSet) list() []*poolHost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*poolHost(nil), s.hosts...)
+}
+
+// hostConn counts the writes to a connection to a host of a pool.
+type hostConn struct {
+	used   int64 // unix nanoseconds of the last write, first to be 64-bit aligned
+	closed int32
+
+	net.Conn
+	host    *poolHost
+	created time.Time
+}
+
+func newHostConn(conn net.Conn, host *poolHost) *hostConn {
+	atomic.AddInt64(&host.open, 1)
+	now := time.Now()
+	return &hostConn{Conn: conn, host: host, created: now, used: now.UnixNano()}
+}
+
+// hostConnOf returns the hostConn of the pooled connection `conn` or nil.
+func hostConnOf(conn net.Conn) *hostConn {
+	for {
+		switch c := conn.(type) {
+		case *hostConn:
+			return c
+		case *pool.PoolConn:
+			conn = c.Conn
+		case *compressConn:
+			conn = c.Conn
+		default:
+			return nil
+		}
+	}
+}
+
+// lastUsed returns the time of the last write to the connection or of its creation.
+func (c *hostConn) lastUsed() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.used))
+}
+
+func (c *hostConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		atomic.AddInt64(&c.host.open, -1)
+	}
+	return c.Conn.Close()
+}
+
+func (c *hostConn) Write(data []byte) (int, error) {
+	atomic.AddInt64(&c.host.outstanding, 1)
+	start := time.Now()
+	n, err := c.Conn.Write(data)
+	atomic.AddInt64(&c.host.outstanding, -1)
+	atomic.StoreInt64(&c.used, time.Now().UnixNano())
+	atomic.AddUint64(&c.host.bytesSent, uint64(n))
+	if err != nil {
+		atomic.AddUint64(&c.host.errors, 1)
+	} else {
+		atomic.AddUint64(&c.host.written, 1)
+		c.host.observe(time.Since(start))
+	}
+	c.host.setDown(err)
+	return n, err
+}
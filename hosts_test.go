@@ -0,0 +1,133 @@
+package logrustash
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHostSetPick(t *testing.T) {
+	s := newHostSet([]string{"ls1:5000", "ls2:5000", "ls3:5000"})
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		picked = append(picked, s.pick("").addr)
+	}
+	expected := []string{"ls1:5000", "ls2:5000", "ls3:5000", "ls1:5000"}
+	for i := range expected {
+		if picked[i] != expected[i] {
+			t.Errorf("expected to see '%v' in '%v'", expected, picked)
+			break
+		}
+	}
+
+	s.hosts[1].setDown(errors.New("down"))
+	for i := 0; i < 4; i++ {
+		if h := s.pick(""); h.addr == "ls2:5000" {
+			t.Error("expected host down to not be picked")
+		}
+	}
+
+	for _, h := range s.hosts {
+		h.setDown(errors.New("down"))
+	}
+	if h := s.pick(""); h == nil {
+		t.Error("expected a host to be picked when all hosts are down")
+	}
+}
+
+func TestPoolHostAvailable(t *testing.T) {
+	h := &poolHost{addr: "ls1:5000"}
+	now := time.Now()
+	if !h.available(now) || !h.up() {
+		t.Error("expected host to be available")
+	}
+
+	h.setDown(errors.New("down"))
+	if h.available(now) || h.up() {
+		t.Error("expected host to be down")
+	}
+	if !h.available(now.Add(hostRetryDelay + time.Second)) {
+		t.Error("expected host to be available after the retry delay")
+	}
+
+	h.setDown(nil)
+	if !h.available(now) || !h.up() {
+		t.Error("expected host to be restored")
+	}
+}
+
+func TestPoolHostRetryDelay(t *testing.T) {
+	h := &poolHost{addr: "ls1:5000"}
+	h.setDown(errors.New("down"))
+	h.setDown(errors.New("down"))
+	if delay := time.Duration(h.retryDelay); delay != hostRetryDelay {
+		t.Errorf("expected the failures before a retry to keep the delay of %s but got %s", hostRetryDelay, delay)
+	}
+
+	for _, expected := range []time.Duration{2 * hostRetryDelay, 4 * hostRetryDelay} {
+		h.failedAt -= int64(h.retryDelay) // the host is retried
+		h.setDown(errors.New("down"))
+		if delay := time.Duration(h.retryDelay); delay != expected {
+			t.Errorf("expected a retry delay of %s but got %s", expected, delay)
+		}
+	}
+
+	h.retryDelay = int64(hostMaxRetryDelay)
+	h.failedAt -= int64(h.retryDelay)
+	h.setDown(errors.New("down"))
+	if delay := time.Duration(h.retryDelay); delay != hostMaxRetryDelay {
+		t.Errorf("expected the retry delay to be capped at %s but got %s", hostMaxRetryDelay, delay)
+	}
+
+	h.setDown(nil)
+	h.setDown(errors.New("down"))
+	if delay := time.Duration(h.retryDelay); delay != hostRetryDelay {
+		t.Errorf("expected the retry delay to be reset to %s but got %s", hostRetryDelay, delay)
+	}
+}
+
+func TestHostSetUpdate(t *testing.T) {
+	s := newHostSet([]string{"ls1:5000", "ls2:5000"})
+	removed := s.hosts[0]
+	kept := s.hosts[1]
+
+	s.update([]string{"ls3:5000", "ls2:5000"})
+
+	var addrs []string
+	for _, h := range s.list() {
+		addrs = append(addrs, h.addr)
+	}
+	expected := []string{"ls2:5000", "ls3:5000"}
+	if len(addrs) != 2 || addrs[0] != expected[0] || addrs[1] != expected[1] {
+		t.Errorf("expected to see '%v' in '%v'", expected, addrs)
+	}
+	if !removed.drained() || kept.drained() || s.hosts[0] != kept {
+		t.Error("expected removed host to be drained and the other kept")
+	}
+
+	s.update(nil)
+	if s.pick("") != nil {
+		t.Error("expected no host to be picked")
+	}
+}
+
+func TestHostSetAddRemove(t *testing.T) {
+	s := newHostSet([]string{"ls1:5000"})
+	s.add("ls2:5000")
+	s.add("ls1:5000")
+	if hosts := s.list(); len(hosts) != 2 || hosts[1].addr != "ls2:5000" {
+		t.Errorf("expected a single host to be added: %v", hosts)
+	}
+
+	removed := s.hosts[0]
+	if s.remove("ls1:5000") != removed || s.remove("ls1:5000") != nil {
+		t.Error("expected host to be removed once")
+	}
+	if !removed.drained() {
+		t.Error("expected removed host to be drained")
+	}
+	if h := s.pick(""); h == nil || h.addr != "ls2:5000" {
+		t.Errorf("expected the remaining host to be picked: %v", h)
+	}
+}
@@ -0,0 +1,175 @@
+package logrustash
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// truncationMarker is appended to values that have been truncated.
+	truncationMarker = "..."
+	// truncatedKey is the field listing the fields that have been truncated or dropped.
+	truncatedKey = "_truncated"
+	// messageKey is the name used in `truncatedKey` for the entry message.
+	messageKey = "message"
+)
+
+// truncateString shortens `s` to at most `n` bytes including the truncation marker,
+// without splitting a UTF-8 sequence.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len(truncationMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncationMarker
+}
+
+// protectedKey reports whether the field `k` is never truncated nor dropped.
+func protectedKey(k string) bool {
+	switch k {
+	case "@version", "type", TagsKey, MetadataKey, truncatedKey:
+		return true
+	}
+	return false
+}
+
+// valueString returns the value `v` of a field as a string, or false if it
+// cannot be truncated: only strings, byte slices, errors and Stringers are,
+// so the structured values keep their type in Elasticsearch.
+func valueString(k string, v interface{}) (string, bool) {
+	if protectedKey(k) {
+		return "", false
+	}
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	case error:
+		return s.Error(), true
+	case fmt.Stringer:
+		return s.String(), true
+	}
+	return "", false
+}
+
+// limit applies the message, field length and number of fields limits to `e`.
+// It returns the sorted names of the affected fields.
+func (f LogstashFormatter) limit(e *logrus.Entry) []string {
+	var truncated []string
+
+	if f.MaxMessageLength > 0 && len(e.Message) > f.MaxMessageLength {
+		e.Message = truncateString(e.Message, f.MaxMessageLength)
+		truncated = addTruncated(truncated, messageKey)
+	}
+
+	if f.MaxFieldLength > 0 {
+		for k, v := range e.Data {
+			if s, ok := valueString(k, v); ok && len(s) > f.MaxFieldLength {
+				e.Data[k] = truncateString(s, f.MaxFieldLength)
+				truncated = addTruncated(truncated, k)
+			}
+		}
+	}
+
+	if f.MaxFields > 0 {
+		// the truncated field counts as one of the fields
+		max := f.MaxFields
+		if len(e.Data) > max || len(truncated) > 0 {
+			max--
+		}
+		truncated = dropFields(e, max, truncated)
+	}
+
+	if len(truncated) > 0 {
+		e.Data[truncatedKey] = truncated
+	}
+	return truncated
+}
+
+// dropFields drops the fields of `e` after the first `max` fields in
+// alphabetical order, keeping the protected fields, and adds them to `truncated`.
+func dropFields(e *logrus.Entry, max int, truncated []string) []string {
+	if len(e.Data) <= max {
+		return truncated
+	}
+	keys := make([]string, 0, len(e.Data))
+	kept := 0
+	for k := range e.Data {
+		if protectedKey(k) {
+			kept++
+		} else {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if max > kept {
+		keys = keys[max-kept:]
+	}
+	for _, k := range keys {
+		delete(e.Data, k)
+		truncated = addTruncated(truncated, k)
+	}
+	return truncated
+}
+
+// shrink truncates the longest values of `e` until the formatted entry
+// fits in `MaxSize` bytes or there is nothing left to truncate.
+func (f LogstashFormatter) shrink(fmter logrus.Formatter, e *logrus.Entry, dataBytes []byte, truncated []string) ([]byte, error) {
+	var err error
+	for len(dataBytes) > f.MaxSize {
+		key, value := longestValue(e)
+		if len(value) <= len(truncationMarker) {
+			break
+		}
+		n := len(value) - (len(dataBytes) - f.MaxSize)
+		if key == "" {
+			e.Message = truncateString(value, n)
+			key = messageKey
+		} else {
+			e.Data[key] = truncateString(value, n)
+		}
+		truncated = addTruncated(truncated, key)
+		if _, ok := e.Data[truncatedKey]; !ok && f.MaxFields > 0 {
+			truncated = dropFields(e, f.MaxFields-1, truncated)
+		}
+		e.Data[truncatedKey] = truncated
+
+		if dataBytes, err = fmter.Format(e); err != nil {
+			return nil, err
+		}
+	}
+	return dataBytes, nil
+}
+
+// longestValue returns the name and the encoded value of the longest value of `e`.
+// An empty name stands for the entry message.
+func longestValue(e *logrus.Entry) (string, string) {
+	key, value := "", e.Message
+	for k, v := range e.Data {
+		if s, ok := valueString(k, v); ok && k != "" && len(s) > len(value) {
+			key, value = k, s
+		}
+	}
+	return key, value
+}
+
+func addTruncated(truncated []string, key string) []string {
+	i := sort.SearchStrings(truncated, key)
+	if i < len(truncated) && truncated[i] == key {
+		return truncated
+	}
+	truncated = append(truncated, "")
+	copy(truncated[i+1:], truncated[i:])
+	truncated[i] = key
+	return truncated
+}
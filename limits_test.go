@@ -0,0 +1,170 @@
+package logrustash
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTruncateString(t *testing.T) {
+	testData := []struct {
+		value    string
+		n        int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"0123456789abc", 10, "0123456..."},
+		{"héllo wörld", 5, "h..."},
+		{"0123456789", 2, "..."},
+	}
+
+	for _, test := range testData {
+		if got := truncateString(test.value, test.n); got != test.expected {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, got)
+		}
+	}
+}
+
+func TestFormatWithLimits(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter:        &logrus.JSONFormatter{},
+		MaxMessageLength: 8,
+		MaxFieldLength:   6,
+		MaxFields:        3,
+	}
+
+	entry := &logrus.Entry{
+		Message: "a very long message",
+		Data:    logrus.Fields{"a": "long value", "b": 1, "c": "dropped"},
+	}
+
+	res, err := f.Format(entry)
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	expected := []string{
+		`"msg":"a ver..."`,
+		`"a":"lon..."`,
+		`"b":1`,
+		`"_truncated":["a","c","message"]`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(string(res), exp) {
+			t.Errorf("expected to have '%s' in '%s'", exp, string(res))
+		}
+	}
+	if strings.Contains(string(res), `"c":`) {
+		t.Errorf("expected c to be dropped from '%s'", string(res))
+	}
+	if entry.Message != "a very long message" || len(entry.Data) != 3 {
+		t.Errorf("expected entry to not be changed: %#v", entry)
+	}
+}
+
+func TestFormatWithMaxSize(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter: &logrus.JSONFormatter{},
+		MaxSize:   120,
+	}
+
+	entry := &logrus.Entry{
+		Message: "message",
+		Data:    logrus.Fields{"payload": strings.Repeat("x", 500)},
+	}
+
+	res, err := f.Format(entry)
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+	if len(res) > f.MaxSize {
+		t.Errorf("expected at most %d bytes but got %d: '%s'", f.MaxSize, len(res), string(res))
+	}
+
+	expected := []string{
+		`"msg":"message"`,
+		`..."`,
+		`"_truncated":["payload"]`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(string(res), exp) {
+			t.Errorf("expected to have '%s' in '%s'", exp, string(res))
+		}
+	}
+}
+
+func TestFormatWithLimitsNonString(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter:      &logrus.JSONFormatter{},
+		MaxFieldLength: 10,
+	}
+
+	entry := logrus.WithError(errors.New(strings.Repeat("e", 1000))).WithFields(logrus.Fields{
+		"ids": make([]int, 3),
+		"req": map[string]interface{}{"path": strings.Repeat("p", 20), "code": 200},
+	})
+	res, err := f.Format(entry)
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	expected := []string{
+		`"error":"eeeeeee..."`,
+		`"ids":[0,0,0]`,
+		`"req":{"code":200,"path":"pppppppppppppppppppp"}`,
+		`"_truncated":["error"]`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(string(res), exp) {
+			t.Errorf("expected to have '%s' in '%s'", exp, string(res))
+		}
+	}
+
+	f = LogstashFormatter{
+		Formatter: &logrus.JSONFormatter{},
+		MaxSize:   200,
+	}
+	if res, err = f.Format(entry); err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+	if len(res) > f.MaxSize {
+		t.Errorf("expected at most %d bytes but got %d: '%s'", f.MaxSize, len(res), string(res))
+	}
+	if !strings.Contains(string(res), expected[2]) {
+		t.Errorf("expected to have '%s' in '%s'", expected[2], string(res))
+	}
+}
+
+func TestFormatWithMaxFields(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter: &logrus.JSONFormatter{},
+		Fields:    logrus.Fields{"@version": "1", "type": "log"},
+		MaxFields: 5,
+	}
+
+	entry := &logrus.Entry{
+		Message: "message",
+		Data:    logrus.Fields{"a": 1, "b": 2, "c": 3, TagsKey: []string{"x"}},
+	}
+	res, err := f.Format(entry)
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(res, &data); err != nil {
+		t.Fatalf("expected Unmarshal to not return error: %s", err)
+	}
+	for _, k := range []string{"@version", "type", TagsKey, "a", truncatedKey} {
+		if _, ok := data[k]; !ok {
+			t.Errorf("expected %s to be kept in '%s'", k, string(res))
+		}
+	}
+	// the fields and the time, level and msg of the JSON formatter
+	if len(data) != f.MaxFields+3 {
+		t.Errorf("expected %d fields but got %d: '%s'", f.MaxFields, len(data)-3, string(res))
+	}
+}
@@ -0,0 +1,101 @@
+// Package expvar publishes the delivery metrics of a logrustash.Hook with
+// the standard expvar package, served as JSON on /debug/vars.
+//
+// To publish the metrics of a hook:
+//
+// hook := logrustash.New(conn, logrustash.DefaultFormatter(logrus.Fields{}))
+// logstashexpvar.Publish(hook, "logstash")
+package expvar
+
+import (
+	stdexpvar "expvar"
+	"sync"
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/kenjones-cisco/logrus-logstash-hook/metrics"
+)
+
+// Exporter holds the metrics of a Hook as an expvar.Var, see the `metrics`
+// package. The metrics with a label are maps by label value and the
+// latency is given as the count and sum in seconds of the deliveries by result.
+// To initialize it use the `NewExporter` or `Publish` functions.
+type Exporter struct {
+	hook    *logrustash.Hook
+	mu      sync.Mutex
+	latency map[string]*latency
+	detach  func()
+}
+
+type latency struct {
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// NewExporter returns an exporter of the metrics of `h`.
+// It is attached to `h` to measure the delivery latency.
+func NewExporter(h *logrustash.Hook) *Exporter {
+	e := &Exporter{hook: h, latency: make(map[string]*latency)}
+	e.detach = metrics.Attach(h, e)
+	return e
+}
+
+// Publish publishes the metrics of `h` as the expvar `name`.
+// Like expvar.Publish, it panics if `name` is already registered.
+func Publish(h *logrustash.Hook, name string) *Exporter {
+	e := NewExporter(h)
+	stdexpvar.Publish(name, e)
+	return e
+}
+
+// Detach stops observing the delivery latency. The expvar package can't
+// remove a published variable: it keeps the last values of the metrics.
+func (e *Exporter) Detach() {
+	e.detach()
+}
+
+// ObserveLatency implements metrics.Sink.
+func (e *Exporter) ObserveLatency(d time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	l, ok := e.latency[metrics.Result(err)]
+	if !ok {
+		l = &latency{}
+		e.latency[metrics.Result(err)] = l
+	}
+	l.Count++
+	l.Sum += d.Seconds()
+}
+
+// Value returns the current value of the metrics.
+func (e *Exporter) Value() map[string]interface{} {
+	v := make(map[string]interface{})
+	for _, s := range metrics.Snapshot(e.hook) {
+		if len(s.LabelValues) == 0 {
+			v[s.Name] = s.Value
+			continue
+		}
+		m, ok := v[s.Name].(map[string]float64)
+		if !ok {
+			m = make(map[string]float64)
+			v[s.Name] = m
+		}
+		m[s.LabelValues[0]] = s.Value
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	lat := make(map[string]latency, len(e.latency))
+	for result, l := range e.latency {
+		lat[result] = *l
+	}
+	v[metrics.Latency.Name] = lat
+	return v
+}
+
+// String implements expvar.Var.
+func (e *Exporter) String() string {
+	return stdexpvar.Func(func() interface{} { return e.Value() }).String()
+}
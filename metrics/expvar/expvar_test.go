@@ -0,0 +1,69 @@
+package expvar
+
+import (
+	"bytes"
+	"encoding/json"
+	stdexpvar "expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/sirupsen/logrus"
+)
+
+type simpleFmter struct{}
+
+func (f simpleFmter) Format(e *logrus.Entry) ([]byte, error) {
+	return []byte(e.Message), nil
+}
+
+func TestPublish(t *testing.T) {
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	h.AddFilter(logrustash.Drop(logrustash.FieldEquals("skip", true)))
+	// expvar.Publish panics on a name already registered, as with -count
+	name := fmt.Sprintf("logstash_test_%d", time.Now().UnixNano())
+	e := Publish(h, name)
+	defer e.Detach()
+
+	_ = h.Fire(&logrus.Entry{Message: "skipped", Data: logrus.Fields{"skip": true}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	v := stdexpvar.Get(name)
+	if v == nil {
+		t.Fatal("expected exporter to be published")
+	}
+
+	var value struct {
+		Fired   float64            `json:"entries_fired_total"`
+		Dropped map[string]float64 `json:"entries_dropped_total"`
+		Latency map[string]struct {
+			Count uint64  `json:"count"`
+			Sum   float64 `json:"sum"`
+		} `json:"delivery_latency_seconds"`
+	}
+	if err := json.Unmarshal([]byte(v.String()), &value); err != nil {
+		t.Fatalf("expected exporter to be valid JSON: %s in '%s'", err, v.String())
+	}
+
+	if value.Fired != 2 {
+		t.Errorf("expected 2 entries fired but got %v", value.Fired)
+	}
+	if value.Dropped["filtered"] != 1 {
+		t.Errorf("expected 1 entry filtered but got %v", value.Dropped)
+	}
+	if value.Latency["success"].Count != 1 {
+		t.Errorf("expected 1 delivery observed but got %v", value.Latency)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	e := NewExporter(h)
+	e.Detach()
+
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	if len(e.latency) != 0 {
+		t.Errorf("expected no delivery observed after Detach but got %v", e.latency)
+	}
+}
@@ -0,0 +1,125 @@
+// Package metrics defines the delivery metrics of a logrustash.Hook shared
+// by the exporters of its subpackages.
+//
+// An exporter pulls the current value of the metrics with `Snapshot` and
+// receives the latency of each delivery as a `Sink` attached to the hook
+// with `Attach`, until detached. Additional backends can be plugged in the same way.
+package metrics
+
+import (
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+)
+
+// Kind is the kind of a metric.
+type Kind int
+
+// Kinds of metrics.
+const (
+	Counter Kind = iota
+	Gauge
+	Histogram
+)
+
+// Definition describes a metric.
+type Definition struct {
+	Name   string
+	Help   string
+	Kind   Kind
+	Labels []string
+}
+
+// Definitions of the metrics of a Hook.
+var (
+	Fired        = &Definition{Name: "entries_fired_total", Help: "Number of entries fired.", Kind: Counter}
+	Formatted    = &Definition{Name: "entries_formatted_total", Help: "Number of entries formatted.", Kind: Counter}
+	Written      = &Definition{Name: "entries_written_total", Help: "Number of entries written.", Kind: Counter}
+	BytesSent    = &Definition{Name: "bytes_sent_total", Help: "Number of bytes of the entries written.", Kind: Counter}
+	FormatErrors = &Definition{Name: "format_errors_total", Help: "Number of entries which failed to be formatted.", Kind: Counter}
+	WriteErrors  = &Definition{Name: "write_errors_total", Help: "Number of entries which failed to be written.", Kind: Counter}
+	Retries      = &Definition{Name: "write_retries_total", Help: "Number of writes retried by the pool.", Kind: Counter}
+	Dropped      = &Definition{Name: "entries_dropped_total", Help: "Number of entries not sent, by reason.", Kind: Counter, Labels: []string{"reason"}}
+	BufferDepth  = &Definition{Name: "buffer_depth", Help: "Number of entries in the async buffer.", Kind: Gauge}
+	HighWater    = &Definition{Name: "buffer_high_water", Help: "Largest number of entries in the async buffer.", Kind: Gauge}
+
+	HostDials       = &Definition{Name: "pool_dials_total", Help: "Number of connections opened to a host.", Kind: Counter, Labels: []string{"host"}}
+	HostDialErrors  = &Definition{Name: "pool_dial_errors_total", Help: "Number of connections to a host which failed.", Kind: Counter, Labels: []string{"host"}}
+	HostWritten     = &Definition{Name: "pool_writes_total", Help: "Number of writes to a host.", Kind: Counter, Labels: []string{"host"}}
+	HostBytesSent   = &Definition{Name: "pool_bytes_sent_total", Help: "Number of bytes written to a host.", Kind: Counter, Labels: []string{"host"}}
+	HostErrors      = &Definition{Name: "pool_write_errors_total", Help: "Number of writes to a host which failed.", Kind: Counter, Labels: []string{"host"}}
+	HostConnections = &Definition{Name: "pool_connections", Help: "Number of connections open to a host.", Kind: Gauge, Labels: []string{"host"}}
+	HostUp          = &Definition{Name: "pool_host_up", Help: "Whether the last connection or write to a host succeeded.", Kind: Gauge, Labels: []string{"host"}}
+
+	// Latency is observed by the sinks, it is not part of a snapshot.
+	Latency = &Definition{Name: "delivery_latency_seconds", Help: "Time elapsed from firing an entry to writing it, by result.", Kind: Histogram, Labels: []string{"result"}}
+)
+
+// Definitions are the metrics of a snapshot.
+var Definitions = []*Definition{
+	Fired, Formatted, Written, BytesSent, FormatErrors, WriteErrors, Retries, Dropped, BufferDepth, HighWater,
+	HostDials, HostDialErrors, HostWritten, HostBytesSent, HostErrors, HostConnections, HostUp,
+}
+
+// Sample is the value of a metric.
+type Sample struct {
+	*Definition
+	LabelValues []string
+	Value       float64
+}
+
+// Snapshot returns the current value of the metrics of `h`.
+func Snapshot(h *logrustash.Hook) []Sample {
+	s := h.Stats()
+	samples := []Sample{
+		{Fired, nil, float64(s.Fired)},
+		{Formatted, nil, float64(s.Formatted)},
+		{Written, nil, float64(s.Written)},
+		{BytesSent, nil, float64(s.BytesSent)},
+		{FormatErrors, nil, float64(s.FormatErrors)},
+		{WriteErrors, nil, float64(s.WriteErrors)},
+		{Retries, nil, float64(s.Retries)},
+		{Dropped, []string{"filtered"}, float64(s.Dropped.Filtered)},
+		{Dropped, []string{"sampled"}, float64(s.Dropped.Sampled)},
+		{Dropped, []string{"duplicated"}, float64(s.Dropped.Duplicated)},
+		{BufferDepth, nil, float64(s.BufferDepth)},
+		{HighWater, nil, float64(s.BufferHighWater)},
+	}
+	for _, hs := range s.Hosts {
+		host := []string{hs.Host}
+		up := 0.0
+		if hs.Up {
+			up = 1
+		}
+		samples = append(samples,
+			Sample{HostDials, host, float64(hs.Dials)},
+			Sample{HostDialErrors, host, float64(hs.DialErrors)},
+			Sample{HostWritten, host, float64(hs.Written)},
+			Sample{HostBytesSent, host, float64(hs.BytesSent)},
+			Sample{HostErrors, host, float64(hs.Errors)},
+			Sample{HostConnections, host, float64(hs.Connections)},
+			Sample{HostUp, host, up},
+		)
+	}
+	return samples
+}
+
+// Result returns the value of the "result" label of the latency of a delivery.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// Sink receives the latency of the deliveries of the hooks it is attached to.
+type Sink interface {
+	ObserveLatency(latency time.Duration, err error)
+}
+
+// Attach sends the latency of the deliveries of `h` to `sink`, in addition
+// to the observer of `h` and the sinks already attached, see
+// `logrustash.Hook.AddObserver`. The returned function detaches the sink.
+func Attach(h *logrustash.Hook, sink Sink) (detach func()) {
+	return h.AddObserver(sink.ObserveLatency)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/sirupsen/logrus"
+)
+
+type simpleFmter struct{}
+
+func (f simpleFmter) Format(e *logrus.Entry) ([]byte, error) {
+	return []byte(e.Message), nil
+}
+
+type countSink struct {
+	count int
+}
+
+func (s *countSink) ObserveLatency(latency time.Duration, err error) {
+	s.count++
+}
+
+func TestSnapshot(t *testing.T) {
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	h.AddFilter(logrustash.Drop(logrustash.FieldEquals("skip", true)))
+
+	_ = h.Fire(&logrus.Entry{Message: "skipped", Data: logrus.Fields{"skip": true}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	samples := Snapshot(h)
+	if len(samples) != 12 {
+		t.Fatalf("expected 12 samples but got %d", len(samples))
+	}
+
+	expected := []Sample{
+		{Fired, nil, 2},
+		{Formatted, nil, 1},
+		{Written, nil, 1},
+		{BytesSent, nil, 4},
+	}
+	if !reflect.DeepEqual(samples[:4], expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, samples[:4])
+	}
+	if s := samples[7]; s.Definition != Dropped || s.LabelValues[0] != "filtered" || s.Value != 1 {
+		t.Errorf("expected one entry filtered but got %v", s)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	observed := 0
+	h.SetObserver(func(time.Duration, error) { observed++ })
+	first := &countSink{}
+	second := &countSink{}
+	detach := Attach(h, first)
+	Attach(h, second)
+
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	detach()
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	if first.count != 1 || second.count != 2 {
+		t.Errorf("expected the sinks to observe 1 and 2 deliveries but got %d and %d", first.count, second.count)
+	}
+	if observed != 2 {
+		t.Errorf("expected the observer of the hook to be kept but got %d deliveries", observed)
+	}
+}
+
+func TestResult(t *testing.T) {
+	if Result(nil) != "success" || Result(logrustash.ErrNoWriter) != "error" {
+		t.Errorf("expected success and error but got %s and %s", Result(nil), Result(logrustash.ErrNoWriter))
+	}
+}
@@ -0,0 +1,93 @@
+// Package otel publishes the delivery metrics of a logrustash.Hook with the
+// OpenTelemetry metrics API.
+//
+// To publish the metrics of a hook with the global meter provider:
+//
+// hook := logrustash.New(conn, logrustash.DefaultFormatter(logrus.Fields{}))
+// exporter, err := logstashotel.NewExporter(hook, otel.Meter("myapp"))
+package otel
+
+import (
+	"context"
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/kenjones-cisco/logrus-logstash-hook/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const prefix = "logstash."
+
+// Exporter records the metrics of a Hook with a meter, see the `metrics`
+// package. The counters and gauges are observed when the meter collects them.
+// The instruments are named logstash.* and the labels are attributes.
+// To initialize it use the `NewExporter` function.
+type Exporter struct {
+	hook         *logrustash.Hook
+	latency      metric.Float64Histogram
+	observables  map[*metrics.Definition]metric.Float64Observable
+	registration metric.Registration
+	detach       func()
+}
+
+// NewExporter returns an exporter of the metrics of `h` to `meter`.
+// It is attached to `h` to measure the delivery latency.
+func NewExporter(h *logrustash.Hook, meter metric.Meter) (*Exporter, error) {
+	e := &Exporter{hook: h, observables: make(map[*metrics.Definition]metric.Float64Observable)}
+
+	var err error
+	e.latency, err = meter.Float64Histogram(prefix+metrics.Latency.Name,
+		metric.WithDescription(metrics.Latency.Help), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	instruments := make([]metric.Observable, 0, len(metrics.Definitions))
+	for _, d := range metrics.Definitions {
+		var o metric.Float64Observable
+		if d.Kind == metrics.Gauge {
+			o, err = meter.Float64ObservableGauge(prefix+d.Name, metric.WithDescription(d.Help))
+		} else {
+			o, err = meter.Float64ObservableCounter(prefix+d.Name, metric.WithDescription(d.Help))
+		}
+		if err != nil {
+			return nil, err
+		}
+		e.observables[d] = o
+		instruments = append(instruments, o)
+	}
+
+	if e.registration, err = meter.RegisterCallback(e.observe, instruments...); err != nil {
+		return nil, err
+	}
+	e.detach = metrics.Attach(h, e)
+	return e, nil
+}
+
+func (e *Exporter) observe(_ context.Context, o metric.Observer) error {
+	for _, s := range metrics.Snapshot(e.hook) {
+		o.ObserveFloat64(e.observables[s.Definition], s.Value, metric.WithAttributes(attributes(s.Labels, s.LabelValues)...))
+	}
+	return nil
+}
+
+// ObserveLatency implements metrics.Sink.
+func (e *Exporter) ObserveLatency(latency time.Duration, err error) {
+	attrs := attributes(metrics.Latency.Labels, []string{metrics.Result(err)})
+	e.latency.Record(context.Background(), latency.Seconds(), metric.WithAttributes(attrs...))
+}
+
+// Unregister stops observing the counters and gauges and the delivery latency.
+func (e *Exporter) Unregister() error {
+	e.detach()
+	return e.registration.Unregister()
+}
+
+func attributes(labels, values []string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, len(labels))
+	for i, l := range labels {
+		attrs[i] = attribute.String(l, values[i])
+	}
+	return attrs
+}
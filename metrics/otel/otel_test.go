@@ -0,0 +1,59 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/sirupsen/logrus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type simpleFmter struct{}
+
+func (f simpleFmter) Format(e *logrus.Entry) ([]byte, error) {
+	return []byte(e.Message), nil
+}
+
+func TestExporter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	e, err := NewExporter(h, provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("expected NewExporter to not return error: %s", err)
+	}
+	defer e.Unregister()
+
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	var rm metricdata.ResourceMetrics
+	if err = reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("expected Collect to not return error: %s", err)
+	}
+
+	found := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = true
+			switch data := m.Data.(type) {
+			case metricdata.Sum[float64]:
+				if m.Name == "logstash.entries_fired_total" && data.DataPoints[0].Value != 1 {
+					t.Errorf("expected 1 entry fired but got %v", data.DataPoints[0].Value)
+				}
+			case metricdata.Histogram[float64]:
+				if data.DataPoints[0].Count != 1 {
+					t.Errorf("expected 1 delivery observed but got %d", data.DataPoints[0].Count)
+				}
+			}
+		}
+	}
+	for _, name := range []string{"logstash.entries_fired_total", "logstash.buffer_depth", "logstash.delivery_latency_seconds"} {
+		if !found[name] {
+			t.Errorf("expected to see metric '%s' in '%v'", name, found)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package prometheus exposes the delivery metrics of a logrustash.Hook
+// as a Prometheus collector.
+//
+// To register the metrics of a hook:
+//
+// hook := logrustash.New(conn, logrustash.DefaultFormatter(logrus.Fields{}))
+// prometheus.MustRegister(logstashprom.NewCollector(hook, "myapp"))
+package prometheus
+
+import (
+	"time"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	"github.com/kenjones-cisco/logrus-logstash-hook/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystem = "logstash"
+
+// Collector is a prometheus.Collector of the metrics of a Hook, see the
+// `metrics` package. To initialize it use the `NewCollector` function.
+type Collector struct {
+	hook    *logrustash.Hook
+	latency *prom.HistogramVec
+	descs   map[*metrics.Definition]*prom.Desc
+}
+
+// NewCollector returns a collector of the metrics of `h`, named
+// `namespace`_logstash_*. It is attached to `h` to measure the delivery latency.
+func NewCollector(h *logrustash.Hook, namespace string) *Collector {
+	c := &Collector{
+		hook: h,
+		latency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      metrics.Latency.Name,
+			Help:      metrics.Latency.Help,
+			Buckets:   prom.DefBuckets,
+		}, metrics.Latency.Labels),
+		descs: make(map[*metrics.Definition]*prom.Desc, len(metrics.Definitions)),
+	}
+	for _, d := range metrics.Definitions {
+		c.descs[d] = prom.NewDesc(prom.BuildFQName(namespace, subsystem, d.Name), d.Help, d.Labels, nil)
+	}
+	metrics.Attach(h, c)
+	return c
+}
+
+// ObserveLatency implements metrics.Sink.
+func (c *Collector) ObserveLatency(latency time.Duration, err error) {
+	c.latency.WithLabelValues(metrics.Result(err)).Observe(latency.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.latency.Describe(ch)
+	for _, d := range metrics.Definitions {
+		ch <- c.descs[d]
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.latency.Collect(ch)
+	for _, s := range metrics.Snapshot(c.hook) {
+		vt := prom.CounterValue
+		if s.Kind == metrics.Gauge {
+			vt = prom.GaugeValue
+		}
+		ch <- prom.MustNewConstMetric(c.descs[s.Definition], vt, s.Value, s.LabelValues...)
+	}
+}
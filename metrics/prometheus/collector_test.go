@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	logrustash "github.com/kenjones-cisco/logrus-logstash-hook"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+type simpleFmter struct{}
+
+func (f simpleFmter) Format(e *logrus.Entry) ([]byte, error) {
+	return []byte(e.Message), nil
+}
+
+func TestCollector(t *testing.T) {
+	h := logrustash.New(bytes.NewBuffer(nil), simpleFmter{})
+	h.AddFilter(logrustash.Drop(logrustash.FieldEquals("skip", true)))
+	c := NewCollector(h, "test")
+
+	_ = h.Fire(&logrus.Entry{Message: "skipped", Data: logrus.Fields{"skip": true}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	reg := prom.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	expected := `
+# HELP test_logstash_bytes_sent_total Number of bytes of the entries written.
+# TYPE test_logstash_bytes_sent_total counter
+test_logstash_bytes_sent_total 4
+# HELP test_logstash_entries_dropped_total Number of entries not sent, by reason.
+# TYPE test_logstash_entries_dropped_total counter
+test_logstash_entries_dropped_total{reason="duplicated"} 0
+test_logstash_entries_dropped_total{reason="filtered"} 1
+test_logstash_entries_dropped_total{reason="sampled"} 0
+# HELP test_logstash_entries_fired_total Number of entries fired.
+# TYPE test_logstash_entries_fired_total counter
+test_logstash_entries_fired_total 2
+# HELP test_logstash_entries_written_total Number of entries written.
+# TYPE test_logstash_entries_written_total counter
+test_logstash_entries_written_total 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"test_logstash_bytes_sent_total", "test_logstash_entries_dropped_total",
+		"test_logstash_entries_fired_total", "test_logstash_entries_written_total")
+	if err != nil {
+		t.Error(err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("expected Gather to not return error: %s", err)
+	}
+	for _, f := range families {
+		if f.GetName() != "test_logstash_delivery_latency_seconds" {
+			continue
+		}
+		if n := f.GetMetric()[0].GetHistogram().GetSampleCount(); n != 1 {
+			t.Errorf("expected one latency observation but got %d", n)
+		}
+		return
+	}
+	t.Error("expected latency histogram")
+}
+
+func TestCollectorPool(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected Listen to not return error: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, aerr := l.Accept()
+			if aerr != nil {
+				return
+			}
+			go io.Copy(ioutil.Discard, conn)
+		}
+	}()
+
+	h := logrustash.New(nil, simpleFmter{})
+	if err = h.UsePool([]string{l.Addr().String()}, 1, 2); err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+	_ = h.Fire(&logrus.Entry{Message: "pooled", Data: logrus.Fields{}})
+
+	host := l.Addr().String()
+	expected := `
+# HELP test_logstash_pool_connections Number of connections open to a host.
+# TYPE test_logstash_pool_connections gauge
+test_logstash_pool_connections{host="` + host + `"} 1
+# HELP test_logstash_pool_writes_total Number of writes to a host.
+# TYPE test_logstash_pool_writes_total counter
+test_logstash_pool_writes_total{host="` + host + `"} 1
+`
+	err = testutil.CollectAndCompare(NewCollector(h, "test"), strings.NewReader(expected),
+		"test_logstash_pool_connections", "test_logstash_pool_writes_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
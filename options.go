@@ -0,0 +1,191 @@
+package logrustash
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoWriter is returned by `NewWithOptions` when neither a writer nor a pool is given.
+var ErrNoWriter = errors.New("a writer or a pool is required")
+
+// Option configures a Hook created by `NewWithOptions`.
+type Option func(*hookOptions)
+
+type hookOptions struct {
+	writer    io.Writer
+	pool      *poolOptions
+	formatter logrus.Formatter
+	levels    []logrus.Level
+	timeout   time.Duration
+	async     bool
+	buffered  bool
+	bufSize   uint
+	filters   []Filter
+	sampler   *Sampler
+	dedup     *Deduplicator
+	observer  Observer
+	threshold float64
+	unhealthy time.Duration
+}
+
+type poolOptions struct {
+	hosts      []string
+	initialCap int
+	maxCap     int
+	opts       []PoolOption
+}
+
+// WithWriter sets the writer the entries are written to.
+func WithWriter(w io.Writer) Option {
+	return func(o *hookOptions) {
+		o.writer = w
+	}
+}
+
+// WithPool writes the entries to a connection pool for `hosts`, see `UsePool`.
+func WithPool(hosts []string, initialCap, maxCap int, opts ...PoolOption) Option {
+	return func(o *hookOptions) {
+		o.pool = &poolOptions{hosts: hosts, initialCap: initialCap, maxCap: maxCap, opts: opts}
+	}
+}
+
+// WithFormatter sets the formatter of the entries.
+// The default is `DefaultFormatter` without additional fields.
+func WithFormatter(f logrus.Formatter) Option {
+	return func(o *hookOptions) {
+		o.formatter = f
+	}
+}
+
+// WithLevels sets the levels to fire the hook. The default is all levels.
+func WithLevels(levels []logrus.Level) Option {
+	return func(o *hookOptions) {
+		o.levels = levels
+	}
+}
+
+// WithTimeout sets the duration of time before writing a message timesout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *hookOptions) {
+		o.timeout = d
+	}
+}
+
+// WithAsync sends the entries asynchronously, see `Async`.
+func WithAsync() Option {
+	return func(o *hookOptions) {
+		o.async = true
+	}
+}
+
+// WithAsyncBuffer sends the entries asynchronously through a buffer, see `AsyncBuffer`.
+func WithAsyncBuffer(bufsize uint) Option {
+	return func(o *hookOptions) {
+		o.async = true
+		o.buffered = true
+		o.bufSize = bufsize
+	}
+}
+
+// WithFilters adds filters deciding which entries are sent, see `AddFilter`.
+func WithFilters(filters ...Filter) Option {
+	return func(o *hookOptions) {
+		o.filters = append(o.filters, filters...)
+	}
+}
+
+// WithSampler sets the sampler deciding which entries are sent, see `SetSampler`.
+func WithSampler(s *Sampler) Option {
+	return func(o *hookOptions) {
+		o.sampler = s
+	}
+}
+
+// WithDeduplicator sets the deduplicator collapsing identical entries, see `SetDeduplicator`.
+func WithDeduplicator(d *Deduplicator) Option {
+	return func(o *hookOptions) {
+		o.dedup = d
+	}
+}
+
+// WithObserver sets the function observing each delivery, see `SetObserver`.
+func WithObserver(fn Observer) Option {
+	return func(o *hookOptions) {
+		o.observer = fn
+	}
+}
+
+// WithBufferThreshold sets the ratio of the capacity of the async buffer
+// above which the hook is not healthy, see `SetBufferThreshold`.
+func WithBufferThreshold(ratio float64) Option {
+	return func(o *hookOptions) {
+		o.threshold = ratio
+	}
+}
+
+// WithUnhealthyAfter sets the duration of time the writes fail before the
+// hook is not healthy, see `SetUnhealthyAfter`.
+func WithUnhealthyAfter(d time.Duration) Option {
+	return func(o *hookOptions) {
+		o.unhealthy = d
+	}
+}
+
+// NewWithOptions returns a new logrus.Hook for Logstash configured by `opts`.
+// The hook is fully configured before it is returned, so it is safe to add it
+// to a logger and use it from several goroutines right away.
+//
+// To create a new hook that sends logs through a pool, in background:
+//
+// hosts := []string{"ls1:5000", "ls2:5000"}
+// hook, err := logrustash.NewWithOptions(logrustash.WithPool(hosts, 5, 10), logrustash.WithAsyncBuffer(10000))
+func NewWithOptions(opts ...Option) (*Hook, error) {
+	o, err := newHookOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Hook{
+		writer:    o.writer,
+		formatter: o.formatter,
+		levels:    o.levels,
+		timeout:   o.timeout,
+		async:     o.async,
+		filters:   o.filters,
+		sampler:   o.sampler,
+		dedup:     o.dedup,
+		observer:  o.observer,
+		threshold: o.threshold,
+		unhealthy: o.unhealthy,
+	}
+	if o.buffered {
+		h.startBuffer(o.bufSize)
+	}
+	return h, nil
+}
+
+// newHookOptions applies `opts` to the default options and creates the pool if any.
+func newHookOptions(opts []Option) (hookOptions, error) {
+	o := hookOptions{levels: logrus.AllLevels}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.pool != nil {
+		p, err := newPool(o.pool.hosts, o.pool.initialCap, o.pool.maxCap, o.pool.opts...)
+		if err != nil {
+			return o, err
+		}
+		o.writer = p
+	}
+	if o.writer == nil {
+		return o, ErrNoWriter
+	}
+	if o.formatter == nil {
+		o.formatter = DefaultFormatter(logrus.Fields{})
+	}
+	return o, nil
+}
@@ -0,0 +1,90 @@
+package logrustash
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	buffer := &syncBuffer{}
+	h, err := NewWithOptions(
+		WithWriter(buffer),
+		WithFormatter(simpleFmter{}),
+		WithLevels([]logrus.Level{logrus.ErrorLevel}),
+		WithTimeout(time.Second),
+		WithAsyncBuffer(10),
+		WithFilters(Drop(FieldEquals("skip", true))),
+		WithUnhealthyAfter(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("expected NewWithOptions to not return error: %s", err)
+	}
+
+	if len(h.Levels()) != 1 || h.timeout != time.Second || h.unhealthy != time.Minute || !h.async || h.buf == nil {
+		t.Errorf("expected hook to be configured: %#v", h)
+	}
+
+	_ = h.Fire(&logrus.Entry{Message: "skipped", Level: logrus.ErrorLevel, Data: logrus.Fields{"skip": true}})
+	_ = h.Fire(&logrus.Entry{Message: "ignored", Level: logrus.InfoLevel, Data: logrus.Fields{}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Level: logrus.ErrorLevel, Data: logrus.Fields{}})
+	h.Flush()
+
+	expected := "msg: \"sent\""
+	if buffer.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, buffer.String())
+	}
+}
+
+func TestNewWithOptionsDefaults(t *testing.T) {
+	h, err := NewWithOptions(WithPool([]string{address}, initCap, maxCap))
+	if err != nil {
+		t.Fatalf("expected NewWithOptions to not return error: %s", err)
+	}
+
+	if _, ok := h.formatter.(LogstashFormatter); !ok {
+		t.Errorf("expected default formatter but got %#v", h.formatter)
+	}
+	if len(h.Levels()) != len(logrus.AllLevels) {
+		t.Errorf("expected all levels but got %v", h.Levels())
+	}
+	if h.async {
+		t.Error("expected hook to be synchronous")
+	}
+}
+
+func TestNewWithOptionsError(t *testing.T) {
+	if _, err := NewWithOptions(); err != ErrNoWriter {
+		t.Errorf("expected to see '%v' in '%v'", ErrNoWriter, err)
+	}
+	if _, err := NewWithOptions(WithPool([]string{"127.0.0.1:7778"}, initCap, maxCap)); err == nil {
+		t.Error("expected NewWithOptions to return error")
+	}
+}
+
+func TestReconfigureWhileFiring(t *testing.T) {
+	h := New(bytes.NewBuffer(nil), simpleFmter{})
+	h.SetLevels(logrus.AllLevels) // use the setters before the hook is shared
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = h.Fire(&logrus.Entry{Message: "msg", Data: logrus.Fields{}})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			h.SetTimeout(time.Duration(i) * time.Millisecond)
+			h.SetLevels(logrus.AllLevels)
+			h.AsyncBuffer(10)
+		}
+	}()
+	wg.Wait()
+	h.Flush()
+}
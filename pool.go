@@ -1,52 +1,411 @@
 package logrustash
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/bitly/go-hostpool"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/fatih/pool.v2"
 )
 
+var (
+	errNoHosts     = errors.New("no hosts in the pool")
+	errHostRemoved = errors.New("host removed from the pool")
+)
+
 // maxRetries is temporary until it is made configurable
 const maxRetries = 3
-const connectTimeOut = 3 // seconds
+
+// PoolOption configures the connection pool created by `UsePool`.
+type PoolOption func(*poolConfig)
+
+type poolConfig struct {
+	compression   Compression
+	dialer        dialer
+	strategy      Strategy
+	probeInterval time.Duration
+	maxLifetime   time.Duration
+	idleTimeout   time.Duration
+
+	resolver        Resolver
+	resolveInterval time.Duration
+}
+
+// PoolCompression compresses the data written to each pooled connection.
+// Every connection carries its own compressed stream.
+func PoolCompression(c Compression) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.compression = c
+	}
+}
+
+// PoolProbe probes the pool every `interval` in background: the idle
+// connections closed by the server are evicted before they are used and the
+// hosts which are down are restored as soon as they accept connections again.
+// Probing is disabled by default.
+func PoolProbe(interval time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.probeInterval = interval
+	}
+}
+
+// PoolMaxLifetime closes the pooled connections older than `d` instead of
+// using them, so the load is rebalanced across the hosts as the connections
+// are recycled. Connections live until they fail by default.
+func PoolMaxLifetime(d time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.maxLifetime = d
+	}
+}
+
+// PoolIdleTimeout closes the pooled connections unused for more than `d`
+// instead of using them, before a load balancer drops them silently.
+// Idle connections are kept by default.
+func PoolIdleTimeout(d time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.idleTimeout = d
+	}
+}
 
 type logstashPool struct {
+	retries    uint64 // first to be 64-bit aligned for atomic operations
+	restoredAt int64  // unix nanoseconds a probe last restored a host
+
 	net.Conn
-	hosts   hostpool.HostPool
-	conns   pool.Pool
-	timeout time.Time
+	hosts     *hostSet
+	conns     *connPool
+	cfg       poolConfig
+	timeout   time.Time
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func newPool(hosts []string, initialCap, maxCap int) (*logstashPool, error) {
-	hpool := hostpool.New(hosts)
-	factory := makeFactory(hpool, len(hosts))
-	conns, err := pool.NewChannelPool(initialCap, maxCap, factory)
-	if err != nil {
+func newPool(hosts []string, initialCap, maxCap int, opts ...PoolOption) (*logstashPool, error) {
+	var cfg poolConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	hset := newHostSet(hosts)
+	if cfg.strategy != nil {
+		hset.strategy = cfg.strategy
+	}
+	if cfg.resolver != nil {
+		if _, err := hset.resolve(cfg.resolver); err != nil {
+			return nil, err
+		}
+	}
+	conns := newConnPool(hset, cfg, maxCap)
+	if err := conns.fill(initialCap); err != nil {
 		return nil, err
 	}
-	return &logstashPool{
-		hosts: hpool,
+	p := &logstashPool{
+		hosts: hset,
 		conns: conns,
-	}, nil
+		cfg:   cfg,
+		done:  make(chan struct{}),
+	}
+	if cfg.probeInterval > 0 {
+		go p.probe(cfg.probeInterval)
+	}
+	if cfg.resolver != nil && cfg.resolveInterval > 0 {
+		go p.discover(cfg.resolver, cfg.resolveInterval)
+	}
+	return p, nil
 }
 
-func makeFactory(hosts hostpool.HostPool, totalHosts int) pool.Factory {
-	return func() (net.Conn, error) {
+// connPool keeps up to maxCap idle connections for the whole pool, grouped by
+// host, and returns a connection to the host selected by the strategy of the pool.
+type connPool struct {
+	hosts  *hostSet
+	cfg    poolConfig
+	maxCap int
+
+	mu    sync.Mutex
+	pools map[*poolHost]pool.Pool // nil once closed
+}
+
+func newConnPool(hosts *hostSet, cfg poolConfig, maxCap int) *connPool {
+	return &connPool{
+		hosts:  hosts,
+		cfg:    cfg,
+		maxCap: maxCap,
+		pools:  make(map[*poolHost]pool.Pool),
+	}
+}
+
+// fill opens `n` connections to the hosts in turn, skipping the hosts which
+// fail to connect.
+func (c *connPool) fill(n int) error {
+	conns := make([]net.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			c.put(conn)
+		}
+	}()
+	hosts := c.hosts.list()
+	for i := 0; i < n; i++ {
+		err := errNoHosts
+		for j := range hosts {
+			var hp pool.Pool
+			if hp, err = c.host(hosts[(i+j)%len(hosts)]); err != nil {
+				break
+			}
+			var conn net.Conn
+			if conn, err = hp.Get(); err == nil {
+				conns = append(conns, conn)
+				break
+			}
+		}
+		if err != nil {
+			c.Close()
+			return fmt.Errorf("factory is not able to fill the pool: %s", err)
+		}
+	}
+	return nil
+}
+
+// Get returns a connection to the host selected for an empty routing key.
+func (c *connPool) Get() (net.Conn, error) {
+	return c.get("")
+}
+
+// get returns a connection to the host selected by the strategy for `key`,
+// or to the next hosts selected if it fails to connect.
+func (c *connPool) get(key string) (net.Conn, error) {
+	err := errNoHosts
+	for i, n := 0, len(c.hosts.list()); i < n; i++ {
+		h := c.hosts.pick(key)
+		if h == nil {
+			return nil, errNoHosts
+		}
+		var hp pool.Pool
+		if hp, err = c.host(h); err == pool.ErrClosed {
+			return nil, err
+		} else if err != nil {
+			continue
+		}
 		var conn net.Conn
+		if conn, err = c.idle(hp); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// host returns the idle connections of `h`.
+func (c *connPool) host(h *poolHost) (pool.Pool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pools == nil {
+		return nil, pool.ErrClosed
+	}
+	if h.drained() {
+		return nil, errHostRemoved
+	}
+	hp, ok := c.pools[h]
+	if !ok {
 		var err error
-		attempts := 0
-		for conn == nil && attempts < totalHosts {
-			attempts++
-			hostresp := hosts.Get()
-			conn, err = net.DialTimeout("tcp", hostresp.Host(), time.Duration(connectTimeOut)*time.Second)
-			if err != nil {
-				hostresp.Mark(err)
+		if hp, err = pool.NewChannelPool(0, c.maxCap, makeFactory(h, c.cfg)); err != nil {
+			return nil, err
+		}
+		c.pools[h] = hp
+	}
+	return hp, nil
+}
+
+// idle returns a connection from `hp`, closing the idle connections which
+// expired, see `PoolMaxLifetime` and `PoolIdleTimeout`.
+func (c *connPool) idle(hp pool.Pool) (net.Conn, error) {
+	for idle := hp.Len(); ; idle-- {
+		conn, err := hp.Get()
+		if err != nil || idle <= 0 || !c.cfg.expired(conn, time.Now()) {
+			return conn, err
+		}
+		markUnusable(conn)
+		_ = conn.Close()
+	}
+}
+
+// put returns the connection `conn` to the idle connections of its host and
+// closes the idle connections above maxCap, those of the other hosts first.
+func (c *connPool) put(conn net.Conn) {
+	_ = conn.Close()
+
+	var keep *poolHost
+	if hc := hostConnOf(conn); hc != nil {
+		keep = hc.host
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		idle, most := 0, 0
+		var evicted pool.Pool
+		for h, hp := range c.pools {
+			n := hp.Len()
+			idle += n
+			if h != keep && n > most {
+				evicted, most = hp, n
 			}
 		}
-		return conn, err
+		if idle <= c.maxCap {
+			return
+		}
+		if evicted == nil {
+			evicted = c.pools[keep]
+		}
+		idleConn, err := evicted.Get()
+		if err != nil {
+			return
+		}
+		markUnusable(idleConn)
+		_ = idleConn.Close()
+	}
+}
+
+// list returns the idle connections of each host.
+func (c *connPool) list() []pool.Pool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pools := make([]pool.Pool, 0, len(c.pools))
+	for _, hp := range c.pools {
+		pools = append(pools, hp)
+	}
+	return pools
+}
+
+// drop closes the idle connections of the removed `hosts`; their connections
+// in use are closed once they are released.
+func (c *connPool) drop(hosts ...*poolHost) {
+	c.mu.Lock()
+	var pools []pool.Pool
+	for _, h := range hosts {
+		if hp, ok := c.pools[h]; ok {
+			pools = append(pools, hp)
+			delete(c.pools, h)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, hp := range pools {
+		hp.Close()
+	}
+}
+
+// Len returns the number of idle connections.
+func (c *connPool) Len() int {
+	n := 0
+	for _, hp := range c.list() {
+		n += hp.Len()
+	}
+	return n
+}
+
+// Close closes the idle connections; the connections in use are closed once
+// they are released.
+func (c *connPool) Close() {
+	c.mu.Lock()
+	pools := c.pools
+	c.pools = nil
+	c.mu.Unlock()
+
+	for _, hp := range pools {
+		hp.Close()
+	}
+}
+
+func makeFactory(h *poolHost, cfg poolConfig) pool.Factory {
+	return func() (net.Conn, error) {
+		conn, err := cfg.dialHost(h.addr)
+		atomic.AddUint64(&h.dials, 1)
+		h.setDown(err)
+		if err != nil {
+			atomic.AddUint64(&h.dialErrors, 1)
+			return nil, err
+		}
+		conn = newHostConn(conn, h)
+		if cfg.compression != NoCompression {
+			return newCompressConn(conn, cfg.compression)
+		}
+		return conn, nil
+	}
+}
+
+func (cfg poolConfig) dialHost(address string) (net.Conn, error) {
+	return cfg.dialer.connect("tcp", address)
+}
+
+// expired reports whether the pooled connection `conn` is too old, idle for
+// too long or to a host removed from the pool.
+func (cfg poolConfig) expired(conn net.Conn, now time.Time) bool {
+	hc := hostConnOf(conn)
+	if hc == nil {
+		return false
+	}
+	return hc.host.drained() ||
+		(cfg.maxLifetime > 0 && now.Sub(hc.created) > cfg.maxLifetime) ||
+		(cfg.idleTimeout > 0 && now.Sub(hc.lastUsed()) > cfg.idleTimeout)
+}
+
+// AddHost adds the host `addr`, "host:port", to the pool.
+// Adding a host already in the pool does nothing.
+func (p *logstashPool) AddHost(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return err
+	}
+	p.hosts.add(addr)
+	return nil
+}
+
+// RemoveHost removes the host `addr` from the pool. Its idle connections are
+// closed and the connections in use are closed once their write completes.
+func (p *logstashPool) RemoveHost(addr string) error {
+	h := p.hosts.remove(addr)
+	if h == nil {
+		return fmt.Errorf("host %s not in the pool", addr)
+	}
+	p.conns.drop(h)
+	return nil
+}
+
+// Hosts returns the addresses of the hosts of the pool.
+func (p *logstashPool) Hosts() []string {
+	var addrs []string
+	for _, h := range p.hosts.list() {
+		addrs = append(addrs, h.addr)
+	}
+	return addrs
+}
+
+// stats returns the counters of the hosts.
+func (p *logstashPool) stats() []HostStats {
+	var stats []HostStats
+	for _, h := range p.hosts.list() {
+		stats = append(stats, h.stats())
+	}
+	return stats
+}
+
+// reachable reports whether the last connection or write to any host succeeded.
+func (p *logstashPool) reachable() bool {
+	for _, h := range p.hosts.list() {
+		if h.up() {
+			return true
+		}
 	}
+	return false
 }
 
 func (p *logstashPool) SetWriteDeadline(t time.Time) error {
@@ -55,43 +414,79 @@ func (p *logstashPool) SetWriteDeadline(t time.Time) error {
 }
 
 func (p *logstashPool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
 	p.conns.Close()
-	p.hosts.Close()
 	return nil
 }
 
 func (p *logstashPool) Write(data []byte) (n int, err error) {
+	return p.writeKey("", data)
+}
+
+// keyed returns a writer of `entry` to the host selected by the strategy of
+// the pool for the value of its field, see `HashField`, or the pool itself.
+func (p *logstashPool) keyed(entry *logrus.Entry) io.Writer {
+	f, ok := p.hosts.strategy.(hashField)
+	if !ok {
+		return p
+	}
+	var key string
+	if v, ok := entry.Data[f.field]; ok {
+		key = fmt.Sprint(v)
+	}
+	return keyWriter{logstashPool: p, key: key}
+}
+
+// writeKey writes `data` to the host selected for `key`, retrying on failure.
+func (p *logstashPool) writeKey(key string, data []byte) (n int, err error) {
 	return p.retry(func() (int, error) {
-		return p.write(data)
+		return p.write(key, data)
 	}, maxRetries)
 }
 
-func (p *logstashPool) write(data []byte) (n int, err error) {
-	conn, err := p.conns.Get()
+func (p *logstashPool) write(key string, data []byte) (n int, err error) {
+	conn, err := p.conns.get(key)
 	if err != nil {
 		return 0, err
 	}
-	defer conn.Close()
+	defer p.conns.put(conn)
 
 	if p.timeout.After(time.Now()) {
 		_ = conn.SetWriteDeadline(p.timeout)
 	}
 
+	// a connection reset or closed by the host, or which timed out, is not reused
 	n, err = conn.Write(data)
-	if netErr, ok := err.(net.Error); ok {
-		if netErr.Temporary() || netErr.Timeout() {
-			if pcon, pok := conn.(*pool.PoolConn); pok {
-				pcon.MarkUnusable()
-			}
-		}
+	if err != nil {
+		markUnusable(conn)
 	}
 
 	return n, err
 }
 
+// keyWriter writes to the host of a pool selected for its routing key.
+type keyWriter struct {
+	*logstashPool
+	key string
+}
+
+func (w keyWriter) Write(data []byte) (int, error) {
+	return w.writeKey(w.key, data)
+}
+
+// markUnusable marks a pooled connection to be closed instead of returned to the pool.
+func markUnusable(conn net.Conn) {
+	if pc, ok := conn.(*pool.PoolConn); ok {
+		pc.MarkUnusable()
+	}
+}
+
 func (p *logstashPool) retry(action func() (int, error), retriesLeft int) (n int, err error) {
 	n, err = action()
 	if err != nil && retriesLeft > 0 {
+		atomic.AddUint64(&p.retries, 1)
 		return p.retry(action, retriesLeft-1)
 	}
 
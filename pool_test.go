@@ -4,6 +4,7 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -144,6 +145,43 @@ func TestWriteError(t *testing.T) {
 
 }
 
+func TestWriteReset(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected Listen to not return error: %s", err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, aerr := l.Accept()
+			if aerr != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	pool, err := newPool([]string{l.Addr().String()}, 1, 1)
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer pool.Close()
+
+	// reset the pooled connection
+	conn := <-accepted
+	_ = conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err = pool.Write([]byte("sample data")); err != nil {
+		t.Errorf("expected Write to redial after the reset but got %s", err)
+	}
+	if s := pool.stats()[0]; s.Dials != 2 || s.Errors != 1 {
+		t.Errorf("expected the reset connection to be replaced: %+v", s)
+	}
+}
+
 func TestWriteTimeout(t *testing.T) {
 	hosts := []string{address}
 	pool, err := newPool(hosts, initCap, maxCap)
@@ -164,3 +202,161 @@ func TestWriteTimeout(t *testing.T) {
 		t.Errorf("expected to see '%d' in '%d'", len(data), n)
 	}
 }
+
+func TestPoolMaxIdle(t *testing.T) {
+	first := listen(t, false)
+	defer first.Close()
+	second := listen(t, false)
+	defer second.Close()
+
+	p, err := newPool([]string{first.Addr().String(), second.Addr().String()}, 0, 2)
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	var conns []net.Conn
+	for i := 0; i < 4; i++ {
+		conn, err := p.conns.Get()
+		if err != nil {
+			t.Fatalf("expected get to not return error: %s", err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		p.conns.put(conn)
+	}
+
+	if n := p.conns.Len(); n != 2 {
+		t.Errorf("expected 2 idle connections for the whole pool but got %d", n)
+	}
+	for _, s := range p.stats() {
+		if s.Connections != 1 {
+			t.Errorf("expected the idle connections of the other hosts to be closed first: %+v", s)
+		}
+	}
+}
+
+func TestPoolMaxLifetime(t *testing.T) {
+	l := listen(t, false)
+	defer l.Close()
+
+	p, err := newPool([]string{l.Addr().String()}, 1, 1, PoolMaxLifetime(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	conn, err := p.conns.Get()
+	if err != nil {
+		t.Fatalf("expected get to not return error: %s", err)
+	}
+	first := hostConnOf(conn)
+	_ = conn.Close()
+
+	if conn, err = p.conns.Get(); err != nil || hostConnOf(conn) != first {
+		t.Errorf("expected the same connection before its max lifetime: %v", err)
+	}
+	_ = conn.Close()
+
+	time.Sleep(30 * time.Millisecond)
+	if conn, err = p.conns.Get(); err != nil || hostConnOf(conn) == first {
+		t.Errorf("expected a new connection after its max lifetime: %v", err)
+	}
+	_ = conn.Close()
+
+	if s := p.stats(); s[0].Connections != 1 || s[0].Dials != 2 {
+		t.Errorf("expected the old connection to be closed: %+v", s[0])
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	l := listen(t, false)
+	defer l.Close()
+
+	p, err := newPool([]string{l.Addr().String()}, 1, 1, PoolIdleTimeout(30*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	conn, err := p.conns.Get()
+	if err != nil {
+		t.Fatalf("expected get to not return error: %s", err)
+	}
+	first := hostConnOf(conn)
+	_ = conn.Close()
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(15 * time.Millisecond)
+		if _, err = p.Write([]byte("data")); err != nil {
+			t.Fatalf("expected Write to not return error: %s", err)
+		}
+	}
+	if conn, err = p.conns.Get(); err != nil || hostConnOf(conn) != first {
+		t.Errorf("expected the same connection while it is used: %v", err)
+	}
+	_ = conn.Close()
+
+	time.Sleep(40 * time.Millisecond)
+	if conn, err = p.conns.Get(); err != nil || hostConnOf(conn) == first {
+		t.Errorf("expected a new connection after its idle timeout: %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestPoolAddRemoveHost(t *testing.T) {
+	first := listen(t, false)
+	defer first.Close()
+	second := listen(t, false)
+	defer second.Close()
+
+	p, err := newPool([]string{first.Addr().String()}, 1, 2)
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	if err = p.AddHost("ls1"); err == nil {
+		t.Error("expected AddHost to return error without a port")
+	}
+	if err = p.AddHost(second.Addr().String()); err != nil {
+		t.Fatalf("expected AddHost to not return error: %s", err)
+	}
+	expected := []string{first.Addr().String(), second.Addr().String()}
+	if hosts := p.Hosts(); len(hosts) != 2 || hosts[0] != expected[0] || hosts[1] != expected[1] {
+		t.Errorf("expected to see '%v' in '%v'", expected, hosts)
+	}
+
+	// a connection to the first host is in use while it is removed
+	conn, err := p.conns.Get()
+	if err != nil {
+		t.Fatalf("expected get to not return error: %s", err)
+	}
+
+	if err = p.RemoveHost(first.Addr().String()); err != nil {
+		t.Fatalf("expected RemoveHost to not return error: %s", err)
+	}
+	if err = p.RemoveHost(first.Addr().String()); err == nil {
+		t.Error("expected RemoveHost to return error for a host not in the pool")
+	}
+	if hosts := p.Hosts(); len(hosts) != 1 || hosts[0] != second.Addr().String() {
+		t.Errorf("expected to see '%v' in '%v'", second.Addr().String(), hosts)
+	}
+
+	if _, err = conn.Write([]byte("data")); err != nil {
+		t.Errorf("expected the write in progress to not return error: %s", err)
+	}
+	_ = conn.Close()
+
+	if _, err = p.Write([]byte("data")); err != nil {
+		t.Fatalf("expected Write to not return error: %s", err)
+	}
+	s := p.stats()
+	if len(s) != 1 || s[0].Host != second.Addr().String() || s[0].Written != 1 {
+		t.Errorf("expected the write to go to the second host: %+v", s)
+	}
+	if n := atomic.LoadInt64(&hostConnOf(conn).host.open); n != 0 {
+		t.Errorf("expected the connections to the removed host to be closed but %d are open", n)
+	}
+}
@@ -0,0 +1,76 @@
+package logrustash
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// probeReadTimeout is the duration of time a probe waits for an idle
+// connection to be closed by the server.
+const probeReadTimeout = time.Millisecond
+
+// probe probes the idle connections and the hosts which are down every
+// `interval` until the pool is closed.
+func (p *logstashPool) probe(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.probeConns()
+			p.probeHosts()
+		}
+	}
+}
+
+// probeConns evicts the idle connections closed by the server or expired.
+func (p *logstashPool) probeConns() {
+	for _, hp := range p.conns.list() {
+		for i, n := 0, hp.Len(); i < n; i++ {
+			conn, err := hp.Get()
+			if err != nil {
+				break
+			}
+			if p.cfg.expired(conn, time.Now()) || !alive(conn) {
+				markUnusable(conn)
+			}
+			_ = conn.Close()
+		}
+	}
+}
+
+// probeHosts restores the hosts which are down and accept connections.
+func (p *logstashPool) probeHosts() {
+	for _, h := range p.hosts.list() {
+		if h.up() {
+			continue
+		}
+		conn, err := p.cfg.dialHost(h.addr)
+		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+		h.setDown(nil)
+		atomic.StoreInt64(&p.restoredAt, time.Now().UnixNano())
+	}
+}
+
+// alive reports whether an idle connection is still open: Logstash does not
+// write to its clients, so a read only returns before its deadline if the
+// connection was closed.
+func alive(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(probeReadTimeout)); err != nil {
+		return false
+	}
+
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	_ = conn.SetReadDeadline(time.Time{})
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	return err == nil
+}
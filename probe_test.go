@@ -0,0 +1,117 @@
+package logrustash
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// listen starts a TCP server which closes the connections it accepts if `hangup`.
+func listen(t *testing.T, hangup bool) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected Listen to not return error: %s", err)
+	}
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, aerr := l.Accept()
+			if aerr != nil {
+				return
+			}
+			if hangup {
+				conn.Close()
+			} else {
+				conns = append(conns, conn)
+			}
+		}
+	}()
+	return l
+}
+
+func TestAlive(t *testing.T) {
+	open := listen(t, false)
+	defer open.Close()
+	closed := listen(t, true)
+	defer closed.Close()
+
+	conn, err := net.Dial("tcp", open.Addr().String())
+	if err != nil {
+		t.Fatalf("expected Dial to not return error: %s", err)
+	}
+	defer conn.Close()
+	if !alive(conn) {
+		t.Error("expected open connection to be alive")
+	}
+
+	conn, err = net.Dial("tcp", closed.Addr().String())
+	if err != nil {
+		t.Fatalf("expected Dial to not return error: %s", err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond) // wait until the server has closed the connection
+	if alive(conn) {
+		t.Error("expected closed connection to not be alive")
+	}
+}
+
+func TestProbeConns(t *testing.T) {
+	l := listen(t, true)
+	defer l.Close()
+
+	p, err := newPool([]string{l.Addr().String()}, 2, 2)
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+	time.Sleep(50 * time.Millisecond) // wait until the server has closed the connections
+
+	p.probeConns()
+	if n := p.conns.Len(); n != 0 {
+		t.Errorf("expected closed connections to be evicted but %d are left", n)
+	}
+}
+
+func TestProbeHosts(t *testing.T) {
+	l := listen(t, false)
+	defer l.Close()
+
+	p, err := newPool([]string{l.Addr().String()}, 1, 1)
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+	defer p.Close()
+
+	host := p.hosts.list()[0]
+	host.setDown(errors.New("down"))
+	p.probeHosts()
+	if !host.up() {
+		t.Error("expected host to be restored")
+	}
+}
+
+func TestPoolProbe(t *testing.T) {
+	l := listen(t, true)
+	defer l.Close()
+
+	p, err := newPool([]string{l.Addr().String()}, 2, 2, PoolProbe(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected newPool to not return error: %s", err)
+	}
+
+	for i := 0; i < 50 && p.conns.Len() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := p.conns.Len(); n != 0 {
+		t.Errorf("expected closed connections to be evicted but %d are left", n)
+	}
+
+	_ = p.Close()
+	_ = p.Close() // closing twice does not panic
+}
@@ -0,0 +1,89 @@
+package logrustash
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reload reconfigures the Hook with `cfg` without losing entries.
+// The new writer or pool is created first; then the entries in flight are
+// written to the old writer, the writer, formatter, levels, timeout, async
+// mode and sampler are swapped and the old writer is closed. The async buffer
+// is recreated, once drained, if its size changes.
+// The filters, the deduplicator and the observers of the Hook are kept.
+func (h *Hook) Reload(cfg Config) error {
+	opts, err := cfg.options()
+	if err != nil {
+		return err
+	}
+	o, err := newHookOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.wg.Wait() // drain the entries in flight to the old writer
+	old := h.writer
+	h.writer = o.writer
+	h.formatter = o.formatter
+	h.levels = o.levels
+	h.timeout = o.timeout
+	h.sampler = o.sampler
+	h.async = o.async
+	if h.buf != nil && (!o.buffered || uint(cap(h.buf)) != bufSize(o.bufSize)) {
+		close(h.buf)
+		h.buf = nil
+	}
+	if o.buffered {
+		h.startBuffer(o.bufSize)
+	}
+	h.mu.Unlock()
+
+	if c, ok := old.(io.Closer); ok && old != o.writer {
+		return c.Close()
+	}
+	return nil
+}
+
+// WatchConfig polls the configuration file `path` every `interval` and
+// reloads the Hook from it and the environment variables when it changes,
+// see `ReadConfig` and `Reload`. Errors are logged and the Hook keeps its
+// current configuration.
+// The returned function stops watching.
+func (h *Hook) WatchConfig(path string, interval time.Duration) (stop func()) {
+	var modTime time.Time
+	if fi, err := os.Stat(path); err == nil {
+		modTime = fi.ModTime()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			fi, err := os.Stat(path)
+			if err != nil || fi.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = fi.ModTime()
+
+			cfg, err := ReadConfig(path)
+			if err == nil {
+				err = h.Reload(cfg)
+			}
+			if err != nil {
+				logrus.Warnf("Error during reloading logstash configuration %s: %v\n", path, err)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
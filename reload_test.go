@@ -0,0 +1,141 @@
+package logrustash
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type closeBuffer struct {
+	syncBuffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	old := &closeBuffer{}
+	h := New(old, simpleFmter{})
+	h.AsyncBuffer(10)
+	h.AddFilter(Drop(FieldEquals("skip", true)))
+
+	for i := 0; i < 5; i++ {
+		_ = h.Fire(&logrus.Entry{Message: "old", Data: logrus.Fields{}})
+	}
+
+	cfg := Config{
+		Hosts:    []string{address},
+		Levels:   []string{"error"},
+		Timeout:  Duration(time.Second),
+		Pool:     &PoolConfig{Initial: 1, Max: 2},
+		Sampling: &SamplingConfig{First: 10},
+	}
+	if err := h.Reload(cfg); err != nil {
+		t.Fatalf("expected Reload to not return error: %s", err)
+	}
+
+	expected := "msg: \"old\"msg: \"old\"msg: \"old\"msg: \"old\"msg: \"old\""
+	if old.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, old.String())
+	}
+	if !old.closed {
+		t.Error("expected old writer to be closed")
+	}
+
+	if _, ok := h.writer.(*logstashPool); !ok {
+		t.Errorf("expected pool writer but got %#v", h.writer)
+	}
+	if _, ok := h.formatter.(LogstashFormatter); !ok {
+		t.Errorf("expected default formatter but got %#v", h.formatter)
+	}
+	if len(h.Levels()) != 3 || h.timeout != time.Second || h.sampler == nil || len(h.filters) != 1 {
+		t.Errorf("expected hook to be reconfigured: %#v", h)
+	}
+	if h.async || h.buf != nil {
+		t.Error("expected hook to be synchronous")
+	}
+}
+
+func TestReloadBufferSize(t *testing.T) {
+	h := New(&closeBuffer{}, simpleFmter{})
+	h.AsyncBuffer(10)
+
+	cfg := Config{Hosts: []string{address}, Pool: &PoolConfig{Initial: 1, Max: 2}, Async: "buffer", BufferSize: 10}
+	if err := h.Reload(cfg); err != nil {
+		t.Fatalf("expected Reload to not return error: %s", err)
+	}
+	buf := h.buf
+	if cap(buf) != 10 {
+		t.Errorf("expected a buffer of 10 but got %d", cap(buf))
+	}
+
+	cfg.BufferSize = 20
+	if err := h.Reload(cfg); err != nil {
+		t.Fatalf("expected Reload to not return error: %s", err)
+	}
+	if h.buf == buf || cap(h.buf) != 20 {
+		t.Errorf("expected a new buffer of 20 but got %d", cap(h.buf))
+	}
+	if err := h.Fire(&logrus.Entry{Message: "new", Data: logrus.Fields{}}); err != nil {
+		t.Errorf("expected Fire to not return error: %s", err)
+	}
+	h.Flush()
+	_ = h.writer.(*logstashPool).Close()
+}
+
+func TestReloadError(t *testing.T) {
+	buffer := &closeBuffer{}
+	h := New(buffer, simpleFmter{})
+
+	if err := h.Reload(Config{}); err == nil {
+		t.Error("expected Reload to return error")
+	}
+	if h.writer != buffer || buffer.closed {
+		t.Error("expected hook to keep its writer")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrustash")
+	if err != nil {
+		t.Fatalf("expected TempDir to not return error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+
+	h := New(&syncBuffer{}, simpleFmter{})
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	log.Hooks.Add(h)
+	stop := h.WatchConfig(path, 10*time.Millisecond)
+	defer stop()
+
+	content := `{"hosts": ["` + address + `"], "levels": ["warn"]}`
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("expected WriteFile to not return error: %s", err)
+	}
+
+	for i := 0; i < 50 && len(h.Levels()) != 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c, ok := h.writer.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	// the levels are read by logrus once, when the hook is added
+	log.Info("below the reloaded levels")
+	log.Warn("written")
+	if s := h.Stats(); s.Fired != 1 || s.Written != 1 {
+		t.Errorf("expected only the warning to be written: %+v", s)
+	}
+}
@@ -0,0 +1,152 @@
+package logrustash
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Route sends the entries with one of its levels to its writer.
+// A route without levels matches all levels.
+// If Match is set, the entries must also satisfy it.
+type Route struct {
+	Name   string
+	Writer io.Writer
+	Levels []logrus.Level
+	Match  func(*logrus.Entry) bool
+}
+
+func (r Route) matches(entry *logrus.Entry) bool {
+	if len(r.Levels) > 0 && !hasLevel(r.Levels, entry.Level) {
+		return false
+	}
+	return r.Match == nil || r.Match(entry)
+}
+
+func hasLevel(levels []logrus.Level, level logrus.Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteHook represents a logrus hook sending entries to different writers
+// depending on their level and fields.
+// Each entry is formatted once and written to the writer of every matching route.
+// To initialize it use the `NewRouteHook` function.
+type RouteHook struct {
+	formatter logrus.Formatter
+	routes    []Route
+	timeout   time.Duration
+	mu        sync.RWMutex
+}
+
+// NewRouteHook returns a new logrus.Hook routing entries formatted by `f` to `routes`.
+//
+// To send errors to a dedicated Logstash pipeline and everything else to another one:
+//
+// errors := logrustash.Route{Name: "errors", Writer: errConn, Levels: []logrus.Level{logrus.ErrorLevel}}
+// others := logrustash.Route{Name: "others", Writer: conn, Levels: []logrus.Level{logrus.InfoLevel, logrus.DebugLevel}}
+// hook := logrustash.NewRouteHook(logrustash.DefaultFormatter(logrus.Fields{}), errors, others)
+func NewRouteHook(f logrus.Formatter, routes ...Route) *RouteHook {
+	return &RouteHook{
+		formatter: f,
+		routes:    routes,
+	}
+}
+
+// AddRoute adds a route to the hook.
+// logrus reads the levels of a hook once, when it is added to a logger, so
+// the routes must be added before: the levels of the routes added later are
+// only fired if another route has them.
+func (h *RouteHook) AddRoute(r Route) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.routes = append(h.routes, r)
+}
+
+// AddPoolRoute adds a route writing to a new connection pool for `hosts`,
+// see `AddRoute`. The pool is closed by `Close`.
+func (h *RouteHook) AddPoolRoute(name string, levels []logrus.Level, hosts []string, initialCap, maxCap int, opts ...PoolOption) error {
+	p, err := newPool(hosts, initialCap, maxCap, opts...)
+	if err != nil {
+		return err
+	}
+	h.AddRoute(Route{Name: name, Writer: p, Levels: levels})
+	return nil
+}
+
+// Close closes the writers of the routes which are io.Closer, such as the
+// pools of `AddPoolRoute`. It returns the first error.
+func (h *RouteHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var firstErr error
+	for _, r := range h.routes {
+		if c, ok := r.Writer.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("route %s: %v", r.Name, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// SetTimeout sets the duration of time before writing a message timesout.
+func (h *RouteHook) SetTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.timeout = d
+}
+
+// Levels returns the levels of all routes.
+func (h *RouteHook) Levels() []logrus.Level {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var levels []logrus.Level
+	for _, r := range h.routes {
+		if len(r.Levels) == 0 {
+			return logrus.AllLevels
+		}
+		for _, l := range r.Levels {
+			if !hasLevel(levels, l) {
+				levels = append(levels, l)
+			}
+		}
+	}
+	return levels
+}
+
+// Fire formats the entry and writes it to the writer of every matching route.
+// All matching routes are written to even if one of them fails; the first error is returned.
+func (h *RouteHook) Fire(entry *logrus.Entry) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var dataBytes []byte
+	var firstErr error
+	for _, r := range h.routes {
+		if !r.matches(entry) {
+			continue
+		}
+		if dataBytes == nil {
+			var err error
+			if dataBytes, err = h.formatter.Format(entry); err != nil {
+				return err
+			}
+		}
+		if err := write(writerOf(r.Writer, entry), h.timeout, dataBytes); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("route %s: %v", r.Name, err)
+		}
+	}
+	return firstErr
+}
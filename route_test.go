@@ -0,0 +1,113 @@
+package logrustash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type countFmter struct {
+	count int
+}
+
+func (f *countFmter) Format(e *logrus.Entry) ([]byte, error) {
+	f.count++
+	return []byte(e.Message), nil
+}
+
+func TestRouteHookFire(t *testing.T) {
+	errBuffer := bytes.NewBuffer(nil)
+	allBuffer := bytes.NewBuffer(nil)
+	auditBuffer := bytes.NewBuffer(nil)
+	f := &countFmter{}
+
+	h := NewRouteHook(f,
+		Route{Name: "errors", Writer: errBuffer, Levels: []logrus.Level{logrus.ErrorLevel}},
+		Route{Name: "all", Writer: allBuffer},
+	)
+	h.AddRoute(Route{
+		Name:   "audit",
+		Writer: auditBuffer,
+		Match: func(e *logrus.Entry) bool {
+			_, ok := e.Data["user"]
+			return ok
+		},
+	})
+
+	if err := h.Fire(&logrus.Entry{Message: "error", Level: logrus.ErrorLevel, Data: logrus.Fields{}}); err != nil {
+		t.Errorf("expected Fire to not return error: %s", err)
+	}
+	if err := h.Fire(&logrus.Entry{Message: "debug", Level: logrus.DebugLevel, Data: logrus.Fields{"user": "bob"}}); err != nil {
+		t.Errorf("expected Fire to not return error: %s", err)
+	}
+
+	if f.count != 2 {
+		t.Errorf("expected entries to be formatted once but got %d calls", f.count)
+	}
+
+	testData := []struct {
+		buffer   *bytes.Buffer
+		expected string
+	}{
+		{errBuffer, "error"},
+		{allBuffer, "errordebug"},
+		{auditBuffer, "debug"},
+	}
+	for _, test := range testData {
+		if test.buffer.String() != test.expected {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, test.buffer.String())
+		}
+	}
+}
+
+func TestRouteHookFireWriteError(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	h := NewRouteHook(simpleFmter{},
+		Route{Name: "broken", Writer: failWrite{}},
+		Route{Name: "ok", Writer: buffer},
+	)
+
+	err := h.Fire(&logrus.Entry{Message: "msg", Data: logrus.Fields{}})
+	if err == nil || !strings.Contains(err.Error(), "route broken") {
+		t.Errorf("expected Fire to return the route error but got %v", err)
+	}
+	if buffer.String() != "msg: \"msg\"" {
+		t.Errorf("expected other routes to be written but got '%s'", buffer.String())
+	}
+}
+
+func TestRouteHookLevels(t *testing.T) {
+	h := NewRouteHook(simpleFmter{},
+		Route{Levels: []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel}},
+		Route{Levels: []logrus.Level{logrus.WarnLevel, logrus.DebugLevel}},
+	)
+
+	if got := h.Levels(); len(got) != 3 {
+		t.Errorf("expected 3 levels but got %v", got)
+	}
+
+	h.AddRoute(Route{})
+	if got := h.Levels(); len(got) != len(logrus.AllLevels) {
+		t.Errorf("expected all levels but got %v", got)
+	}
+}
+
+func TestRouteHookClose(t *testing.T) {
+	closer := &closeBuffer{}
+	h := NewRouteHook(simpleFmter{}, Route{Name: "closer", Writer: closer}, Route{Name: "buffer", Writer: bytes.NewBuffer(nil)})
+	if err := h.AddPoolRoute("pool", nil, []string{address}, 1, 1); err != nil {
+		t.Fatalf("expected AddPoolRoute to not return error: %s", err)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Errorf("expected Close to not return error: %s", err)
+	}
+	if !closer.closed {
+		t.Error("expected the writer of the route to be closed")
+	}
+	if _, err := h.routes[2].Writer.(*logstashPool).conns.Get(); err == nil {
+		t.Error("expected the pool of the route to be closed")
+	}
+}
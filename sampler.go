@@ -0,0 +1,195 @@
+package logrustash
+
+import (
+	"container/list"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const samplingSummaryMessage = "log entries suppressed by sampling"
+
+// defaultMaxMessages is the number of messages tracked by a Sampler by default.
+const defaultMaxMessages = 10000
+
+// Sampler decides which entries are sent to Logstash and counts the suppressed ones.
+// Entries are grouped by level and message; the message is the template of the
+// entry since the variable parts are usually in the fields.
+//
+// Rates is the probability, between 0 and 1, to send an entry of a level;
+// levels without a rate are always sent.
+// Limit is the number of entries per second sent for each message, with bursts
+// of up to Burst entries.
+// First entries of each message are sent, then only every Thereafter-th entry.
+//
+// MaxMessages is the number of messages tracked, 10000 by default; beyond it,
+// the least recently seen message is forgotten and starts over when seen again.
+//
+// Every SummaryInterval, a summary entry reporting the entries suppressed since
+// the previous summary is sent, as long as entries are suppressed. The zero
+// value sends every entry.
+type Sampler struct {
+	Rates           map[logrus.Level]float64
+	Limit           float64
+	Burst           int
+	First           int
+	Thereafter      int
+	MaxMessages     int
+	SummaryInterval time.Duration
+
+	mu       sync.Mutex
+	messages map[sampleKey]*messageSample
+	recent   *list.List // keys of the messages, the most recently seen first
+	evicted  uint64     // suppressed entries of the messages forgotten
+	ticking  bool
+}
+
+type sampleKey struct {
+	level   logrus.Level
+	message string
+}
+
+type messageSample struct {
+	elem       *list.Element
+	count      int
+	tokens     float64
+	lastRefill time.Time
+	suppressed uint64
+}
+
+// sample reports whether the entry `e` is sent. While entries are suppressed,
+// the summary is passed to `emit` every SummaryInterval.
+func (s *Sampler) sample(e *logrus.Entry, now time.Time, emit func(*logrus.Entry)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.messages == nil {
+		s.messages = make(map[sampleKey]*messageSample)
+		s.recent = list.New()
+	}
+	key := sampleKey{e.Level, e.Message}
+	ms, ok := s.messages[key]
+	if ok {
+		s.recent.MoveToFront(ms.elem)
+	} else {
+		s.evict()
+		ms = &messageSample{tokens: float64(s.burst()), lastRefill: now}
+		ms.elem = s.recent.PushFront(key)
+		s.messages[key] = ms
+	}
+
+	if !s.keep(e, ms, now) {
+		ms.suppressed++
+		if s.SummaryInterval > 0 && !s.ticking {
+			s.ticking = true
+			go s.tick(emit)
+		}
+		return false
+	}
+	return true
+}
+
+// evict forgets the least recently seen messages to make room for a new one.
+func (s *Sampler) evict() {
+	max := s.MaxMessages
+	if max <= 0 {
+		max = defaultMaxMessages
+	}
+	for s.recent.Len() >= max {
+		key := s.recent.Remove(s.recent.Back()).(sampleKey)
+		s.evicted += s.messages[key].suppressed
+		delete(s.messages, key)
+	}
+}
+
+// tick passes the summary to `emit` every SummaryInterval until no entries
+// were suppressed during an interval.
+func (s *Sampler) tick(emit func(*logrus.Entry)) {
+	ticker := time.NewTicker(s.SummaryInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		summary := s.summary(now)
+		if summary == nil {
+			return
+		}
+		emit(summary)
+	}
+}
+
+func (s *Sampler) keep(e *logrus.Entry, ms *messageSample, now time.Time) bool {
+	ms.count++
+	if (s.First > 0 || s.Thereafter > 0) && ms.count > s.First {
+		if s.Thereafter <= 0 || (ms.count-s.First)%s.Thereafter != 0 {
+			return false
+		}
+	}
+
+	if s.Limit > 0 {
+		ms.tokens += now.Sub(ms.lastRefill).Seconds() * s.Limit
+		if ms.tokens > float64(s.burst()) {
+			ms.tokens = float64(s.burst())
+		}
+		ms.lastRefill = now
+		if ms.tokens < 1 {
+			return false
+		}
+		ms.tokens--
+	}
+
+	if rate, ok := s.Rates[e.Level]; ok && rand.Float64() >= rate {
+		return false
+	}
+	return true
+}
+
+func (s *Sampler) burst() int {
+	if s.Burst < 1 {
+		return 1
+	}
+	return s.Burst
+}
+
+// summary returns an entry reporting the entries suppressed since the last summary
+// and resets the suppressed counts. It returns nil and stops the summaries if
+// no entries were suppressed.
+func (s *Sampler) summary(now time.Time) *logrus.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.evicted
+	var suppressed []map[string]interface{}
+	for key, ms := range s.messages {
+		if ms.suppressed == 0 {
+			continue
+		}
+		total += ms.suppressed
+		suppressed = append(suppressed, map[string]interface{}{
+			"level":   key.level.String(),
+			"message": key.message,
+			"count":   ms.suppressed,
+		})
+		ms.suppressed = 0
+	}
+	s.evicted = 0
+
+	if total == 0 {
+		s.ticking = false
+		return nil
+	}
+	sort.Slice(suppressed, func(i, j int) bool {
+		return suppressed[i]["count"].(uint64) > suppressed[j]["count"].(uint64)
+	})
+	return &logrus.Entry{
+		Time:    now,
+		Level:   logrus.InfoLevel,
+		Message: samplingSummaryMessage,
+		Data: logrus.Fields{
+			"sampling.total":      total,
+			"sampling.suppressed": suppressed,
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package logrustash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func sampleN(s *Sampler, e *logrus.Entry, n int, now time.Time) int {
+	sent := 0
+	for i := 0; i < n; i++ {
+		if s.sample(e, now, nil) {
+			sent++
+		}
+	}
+	return sent
+}
+
+func TestSamplerFirstThereafter(t *testing.T) {
+	s := &Sampler{First: 3, Thereafter: 10}
+	entry := &logrus.Entry{Message: "chatty", Level: logrus.InfoLevel}
+
+	if sent := sampleN(s, entry, 103, time.Now()); sent != 13 {
+		t.Errorf("expected 13 entries to be sent but got %d", sent)
+	}
+	other := &logrus.Entry{Message: "other", Level: logrus.InfoLevel}
+	if !s.sample(other, time.Now(), nil) {
+		t.Error("expected first entry of another message to be sent")
+	}
+}
+
+func TestSamplerRates(t *testing.T) {
+	s := &Sampler{Rates: map[logrus.Level]float64{logrus.DebugLevel: 0, logrus.InfoLevel: 1}}
+
+	if sent := sampleN(s, &logrus.Entry{Message: "m", Level: logrus.DebugLevel}, 10, time.Now()); sent != 0 {
+		t.Errorf("expected no debug entries to be sent but got %d", sent)
+	}
+	if sent := sampleN(s, &logrus.Entry{Message: "m", Level: logrus.InfoLevel}, 10, time.Now()); sent != 10 {
+		t.Errorf("expected all info entries to be sent but got %d", sent)
+	}
+	if sent := sampleN(s, &logrus.Entry{Message: "m", Level: logrus.ErrorLevel}, 10, time.Now()); sent != 10 {
+		t.Errorf("expected all error entries to be sent but got %d", sent)
+	}
+}
+
+func TestSamplerLimit(t *testing.T) {
+	s := &Sampler{Limit: 2, Burst: 5}
+	entry := &logrus.Entry{Message: "limited"}
+	now := time.Now()
+
+	if sent := sampleN(s, entry, 10, now); sent != 5 {
+		t.Errorf("expected a burst of 5 entries to be sent but got %d", sent)
+	}
+	if sent := sampleN(s, entry, 10, now.Add(time.Second)); sent != 2 {
+		t.Errorf("expected 2 entries to be sent after a second but got %d", sent)
+	}
+}
+
+func TestSamplerSummary(t *testing.T) {
+	s := &Sampler{First: 1}
+	entry := &logrus.Entry{Message: "chatty", Level: logrus.WarnLevel}
+	now := time.Now()
+
+	sampleN(s, entry, 5, now)
+
+	summary := s.summary(now.Add(time.Minute))
+	if summary == nil {
+		t.Fatal("expected a summary of the suppressed entries")
+	}
+	if summary.Data["sampling.total"] != uint64(4) {
+		t.Errorf("expected 4 suppressed entries but got %v", summary.Data["sampling.total"])
+	}
+	suppressed := summary.Data["sampling.suppressed"].([]map[string]interface{})
+	if len(suppressed) != 1 || suppressed[0]["message"] != "chatty" || suppressed[0]["level"] != "warning" {
+		t.Errorf("expected suppressed counts of the message but got %v", suppressed)
+	}
+
+	if summary = s.summary(now.Add(2 * time.Minute)); summary != nil {
+		t.Errorf("expected the suppressed counts to be reset: %#v", summary)
+	}
+
+	// only the suppressed counts are reset
+	if s.sample(entry, now.Add(time.Minute), nil) {
+		t.Error("expected the entry after a summary to be suppressed")
+	}
+}
+
+func TestSamplerMaxMessages(t *testing.T) {
+	s := &Sampler{First: 1, MaxMessages: 2}
+	now := time.Now()
+
+	sampleN(s, &logrus.Entry{Message: "first"}, 2, now)
+	sampleN(s, &logrus.Entry{Message: "second"}, 1, now)
+	sampleN(s, &logrus.Entry{Message: "third"}, 1, now)
+
+	if len(s.messages) != 2 || s.recent.Len() != 2 {
+		t.Errorf("expected 2 messages to be tracked but got %d", len(s.messages))
+	}
+	if _, ok := s.messages[sampleKey{message: "first"}]; ok {
+		t.Error("expected the least recently seen message to be forgotten")
+	}
+	if summary := s.summary(now); summary == nil || summary.Data["sampling.total"] != uint64(1) {
+		t.Errorf("expected the suppressed entries of the forgotten message to be reported: %v", summary)
+	}
+}
+
+func TestFireWithSampler(t *testing.T) {
+	buffer := &syncBuffer{}
+	h := New(buffer, simpleFmter{})
+	h.SetSampler(&Sampler{First: 1, SummaryInterval: 20 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		if err := h.Fire(&logrus.Entry{Message: "chatty", Data: logrus.Fields{}}); err != nil {
+			t.Errorf("expected Fire to not return error: %s", err)
+		}
+	}
+
+	// the summary is sent without further entries
+	expected := "msg: \"chatty\"msg: \"" + samplingSummaryMessage + "\""
+	for i := 0; i < 50 && buffer.String() != expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if buffer.String() != expected {
+		t.Errorf("expected to see '%s' in '%s'", expected, buffer.String())
+	}
+}
@@ -0,0 +1,175 @@
+package logrustash
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldType is the Elasticsearch type a field value is coerced to.
+type FieldType int
+
+// Supported field types.
+const (
+	TypeString FieldType = iota + 1
+	TypeLong
+	TypeDouble
+	TypeBool
+	TypeDate
+	TypeKeywords
+)
+
+// invalidFieldPrefix is prepended to the name of a field whose value
+// could not be coerced when the schema is strict.
+const invalidFieldPrefix = "_invalid."
+
+// Schema declares the type of fields by name.
+// Fields that are not declared are left untouched.
+type Schema map[string]FieldType
+
+// apply coerces the values in `data` to the types declared in the schema.
+// A value that cannot be coerced is kept as is unless `strict` is set,
+// in which case it is moved to "_invalid.<name>".
+func (s Schema) apply(data logrus.Fields, strict bool) {
+	for k, t := range s {
+		v, ok := data[k]
+		if !ok || v == nil {
+			continue
+		}
+		cv, err := t.coerce(v)
+		if err == nil {
+			data[k] = cv
+			continue
+		}
+		if strict {
+			delete(data, k)
+			data[invalidFieldPrefix+k] = v
+		}
+	}
+}
+
+func (t FieldType) coerce(v interface{}) (interface{}, error) {
+	switch t {
+	case TypeString:
+		return toString(v), nil
+	case TypeLong:
+		return toLong(v)
+	case TypeDouble:
+		return toDouble(v)
+	case TypeBool:
+		return toBool(v)
+	case TypeDate:
+		return toDate(v)
+	case TypeKeywords:
+		return toKeywords(v), nil
+	}
+	return nil, fmt.Errorf("unknown field type %d", t)
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func toLong(v interface{}) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows long", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+			return 0, fmt.Errorf("%v is not a long", f)
+		}
+		return int64(f), nil
+	case reflect.String:
+		return strconv.ParseInt(rv.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to long", v)
+}
+
+func toDouble(v interface{}) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return strconv.ParseFloat(rv.String(), 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to double", v)
+}
+
+func toBool(v interface{}) (bool, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return strconv.ParseBool(rv.String())
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", v)
+}
+
+// toDate converts times, RFC3339 strings and epoch milliseconds
+// into an RFC3339 string with nanosecond precision.
+func toDate(v interface{}) (string, error) {
+	switch d := v.(type) {
+	case time.Time:
+		return d.Format(time.RFC3339Nano), nil
+	case *time.Time:
+		if d == nil {
+			return "", fmt.Errorf("nil time")
+		}
+		return d.Format(time.RFC3339Nano), nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, d)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+	ms, err := toLong(v)
+	if err != nil {
+		return "", fmt.Errorf("cannot convert %T to date", v)
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano), nil
+}
+
+// toKeywords converts a value or a slice of values into a slice of strings.
+func toKeywords(v interface{}) []string {
+	switch k := v.(type) {
+	case []string:
+		return k
+	case string:
+		return []string{k}
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []string{toString(v)}
+	}
+	keywords := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		keywords = append(keywords, toString(rv.Index(i).Interface()))
+	}
+	return keywords
+}
@@ -0,0 +1,111 @@
+package logrustash
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSchemaCoerce(t *testing.T) {
+	now := time.Date(2017, 8, 25, 14, 38, 22, 0, time.UTC)
+
+	testData := []struct {
+		typ      FieldType
+		value    interface{}
+		expected interface{}
+	}{
+		{TypeString, 200, "200"},
+		{TypeString, errors.New("boom"), "boom"},
+		{TypeLong, "200", int64(200)},
+		{TypeLong, uint8(7), int64(7)},
+		{TypeLong, 3.0, int64(3)},
+		{TypeDouble, "1.5", 1.5},
+		{TypeDouble, 2, 2.0},
+		{TypeBool, "true", true},
+		{TypeBool, false, false},
+		{TypeDate, now, "2017-08-25T14:38:22Z"},
+		{TypeDate, "2017-08-25T14:38:22Z", "2017-08-25T14:38:22Z"},
+		{TypeDate, int64(1503671902000), "2017-08-25T14:38:22Z"},
+		{TypeKeywords, "a", []string{"a"}},
+		{TypeKeywords, []interface{}{"a", 1}, []string{"a", "1"}},
+	}
+
+	for _, test := range testData {
+		got, err := test.typ.coerce(test.value)
+		if err != nil {
+			t.Errorf("expected coerce of %#v to not return error: %s", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %#v to be coerced to %#v but got %#v", test.value, test.expected, got)
+		}
+	}
+}
+
+func TestSchemaCoerceError(t *testing.T) {
+	testData := []struct {
+		typ   FieldType
+		value interface{}
+	}{
+		{TypeLong, "OK"},
+		{TypeLong, 1.5},
+		{TypeLong, uint64(1 << 63)},
+		{TypeLong, float64(1 << 63)},
+		{TypeDouble, true},
+		{TypeBool, "maybe"},
+		{TypeDate, "yesterday"},
+	}
+
+	for _, test := range testData {
+		if _, err := test.typ.coerce(test.value); err == nil {
+			t.Errorf("expected coerce of %#v to return error", test.value)
+		}
+	}
+}
+
+func TestFormatWithSchema(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter: &logrus.JSONFormatter{},
+		Fields:    logrus.Fields{"status": "200"},
+		Schema:    Schema{"status": TypeLong, "ok": TypeBool},
+	}
+
+	res, err := f.Format(&logrus.Entry{Data: logrus.Fields{"ok": "bad"}})
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	expected := []string{
+		`"status":200`,
+		`"ok":"bad"`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(string(res), exp) {
+			t.Errorf("expected to have '%s' in '%s'", exp, string(res))
+		}
+	}
+}
+
+func TestFormatWithStrictSchema(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter:    &logrus.JSONFormatter{},
+		Schema:       Schema{"status": TypeLong},
+		StrictSchema: true,
+	}
+
+	res, err := f.Format(&logrus.Entry{Data: logrus.Fields{"status": "OK"}})
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	if !strings.Contains(string(res), `"_invalid.status":"OK"`) {
+		t.Errorf("expected invalid status to be moved in '%s'", string(res))
+	}
+	if strings.Contains(string(res), `"status":"OK"`) {
+		t.Errorf("expected status to be removed from '%s'", string(res))
+	}
+}
@@ -0,0 +1,109 @@
+package logrustash
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds the delivery counters of a Hook.
+//
+// Fired is the number of entries fired and Dropped the number of them not
+// sent by reason. Formatted, Written and BytesSent count the entries formatted,
+// the entries written and their size; FormatErrors and WriteErrors the failures.
+// Retries is the number of writes retried by the pool.
+// BufferDepth is the number of entries in the async buffer and
+// BufferHighWater the largest number of entries it held.
+// Hosts holds the counters of each host of the pool.
+type Stats struct {
+	Fired           uint64
+	Formatted       uint64
+	Written         uint64
+	BytesSent       uint64
+	FormatErrors    uint64
+	WriteErrors     uint64
+	Retries         uint64
+	Dropped         DropStats
+	BufferDepth     int
+	BufferHighWater int
+	Hosts           []HostStats
+}
+
+// DropStats holds the number of entries not sent by reason:
+// rejected by a filter, by the sampler or collapsed by the deduplicator.
+type DropStats struct {
+	Filtered   uint64
+	Sampled    uint64
+	Duplicated uint64
+}
+
+// HostStats holds the counters of a host of a pool: the connections opened
+// and failed, the writes and their size, the failed writes and the number of
+// connections currently open. Up reports whether the last connection or write
+// to the host succeeded.
+type HostStats struct {
+	Host        string
+	Dials       uint64
+	DialErrors  uint64
+	Written     uint64
+	BytesSent   uint64
+	Errors      uint64
+	Connections int
+	Up          bool
+}
+
+// Observer is called after each delivery, successful or not, with the time
+// elapsed since the entry was fired, including the time spent in the async
+// buffer, and the format or write error if any.
+type Observer func(latency time.Duration, err error)
+
+type hookStats struct {
+	fired        uint64
+	formatted    uint64
+	written      uint64
+	bytesSent    uint64
+	formatErrors uint64
+	writeErrors  uint64
+	filtered     uint64
+	sampled      uint64
+	duplicated   uint64
+	highWater    int64
+	failedAt     int64 // unix nanoseconds of the first write failure, 0 if the last write succeeded
+}
+
+// buffered records the depth of the async buffer after an entry was pushed.
+func (s *hookStats) buffered(depth int) {
+	for {
+		hw := atomic.LoadInt64(&s.highWater)
+		if int64(depth) <= hw || atomic.CompareAndSwapInt64(&s.highWater, hw, int64(depth)) {
+			return
+		}
+	}
+}
+
+// Stats returns the delivery counters of the Hook.
+// It is safe to call it while entries are fired.
+func (h *Hook) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s := Stats{
+		Fired:        atomic.LoadUint64(&h.stats.fired),
+		Formatted:    atomic.LoadUint64(&h.stats.formatted),
+		Written:      atomic.LoadUint64(&h.stats.written),
+		BytesSent:    atomic.LoadUint64(&h.stats.bytesSent),
+		FormatErrors: atomic.LoadUint64(&h.stats.formatErrors),
+		WriteErrors:  atomic.LoadUint64(&h.stats.writeErrors),
+		Dropped: DropStats{
+			Filtered:   atomic.LoadUint64(&h.stats.filtered),
+			Sampled:    atomic.LoadUint64(&h.stats.sampled),
+			Duplicated: atomic.LoadUint64(&h.stats.duplicated),
+		},
+		BufferDepth:     len(h.buf),
+		BufferHighWater: int(atomic.LoadInt64(&h.stats.highWater)),
+	}
+	if p, ok := h.writer.(*logstashPool); ok {
+		s.Retries = atomic.LoadUint64(&p.retries)
+		s.Hosts = p.stats()
+	}
+	return s
+}
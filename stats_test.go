@@ -0,0 +1,121 @@
+package logrustash
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStats(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	h := New(buffer, simpleFmter{})
+	h.AddFilter(Drop(FieldEquals("skip", true)))
+	h.SetSampler(&Sampler{First: 1})
+
+	_ = h.Fire(&logrus.Entry{Message: "skipped", Data: logrus.Fields{"skip": true}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	h.formatter = failFmt{}
+	_ = h.Fire(&logrus.Entry{Message: "format failed", Data: logrus.Fields{}})
+	h.formatter = simpleFmter{}
+	h.writer = failWrite{}
+	_ = h.Fire(&logrus.Entry{Message: "write failed", Data: logrus.Fields{}})
+
+	expected := Stats{
+		Fired:        5,
+		Formatted:    2,
+		Written:      1,
+		BytesSent:    uint64(len("msg: \"sent\"")),
+		FormatErrors: 1,
+		WriteErrors:  1,
+		Dropped:      DropStats{Filtered: 1, Sampled: 1},
+	}
+	if s := h.Stats(); !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected to see '%+v' in '%+v'", expected, s)
+	}
+}
+
+func TestStatsBuffer(t *testing.T) {
+	release := make(chan struct{})
+	h := New(blockingWriter{release: release}, simpleFmter{})
+	h.AsyncBuffer(10)
+
+	for i := 0; i < 4; i++ {
+		_ = h.Fire(&logrus.Entry{Message: "buffered", Data: logrus.Fields{}})
+	}
+
+	s := h.Stats()
+	if s.BufferDepth < 3 || s.BufferHighWater < 3 {
+		t.Errorf("expected buffered entries but got depth %d and high-water %d", s.BufferDepth, s.BufferHighWater)
+	}
+
+	close(release)
+	h.Flush()
+
+	s = h.Stats()
+	if s.BufferDepth != 0 || s.BufferHighWater < 3 || s.Written != 4 {
+		t.Errorf("expected flushed entries but got %+v", s)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	var latencies []time.Duration
+	var errs []error
+	h := New(bytes.NewBuffer(nil), simpleFmter{})
+	h.SetObserver(func(latency time.Duration, err error) {
+		latencies = append(latencies, latency)
+		errs = append(errs, err)
+	})
+
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	h.writer = failWrite{}
+	_ = h.Fire(&logrus.Entry{Message: "failed", Data: logrus.Fields{}})
+
+	if len(latencies) != 2 || latencies[0] < 0 || errs[0] != nil || errs[1] == nil {
+		t.Errorf("expected two observed deliveries but got %v and %v", latencies, errs)
+	}
+}
+
+func TestAddObserver(t *testing.T) {
+	var observed []string
+	h := New(bytes.NewBuffer(nil), simpleFmter{})
+	h.SetObserver(func(time.Duration, error) { observed = append(observed, "set") })
+	remove := h.AddObserver(func(time.Duration, error) { observed = append(observed, "added") })
+
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+	remove()
+	_ = h.Fire(&logrus.Entry{Message: "sent", Data: logrus.Fields{}})
+
+	expected := []string{"set", "added", "set"}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, observed)
+	}
+}
+
+func TestStatsPool(t *testing.T) {
+	h := New(nil, simpleFmter{})
+	if err := h.UsePool([]string{address}, 1, 2); err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+
+	if err := h.Fire(&logrus.Entry{Message: "pooled", Data: logrus.Fields{}}); err != nil {
+		t.Fatalf("expected Fire to not return error: %s", err)
+	}
+
+	s := h.Stats()
+	if len(s.Hosts) != 1 {
+		t.Fatalf("expected stats of one host but got %+v", s.Hosts)
+	}
+	expected := HostStats{Host: address, Dials: 1, Written: 1, BytesSent: uint64(len("msg: \"pooled\"")), Connections: 1, Up: true}
+	if s.Hosts[0] != expected {
+		t.Errorf("expected to see '%+v' in '%+v'", expected, s.Hosts[0])
+	}
+
+	_ = h.writer.(*logstashPool).Close()
+	if s = h.Stats(); s.Hosts[0].Connections != 0 {
+		t.Errorf("expected no open connections but got %d", s.Hosts[0].Connections)
+	}
+}
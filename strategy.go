@@ -0,0 +1,162 @@
+package logrustash
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Strategy selects the host of a pool each entry is written to, see `PoolStrategy`.
+type Strategy interface {
+	// pick returns one of `hosts`, which is not empty, for the entry with the routing `key`.
+	pick(hosts []*poolHost, key string) *poolHost
+}
+
+// PoolStrategy sets the strategy selecting the host each entry is written to.
+// The hosts which are down are skipped by every strategy until they are
+// restored. The default is RoundRobin.
+func PoolStrategy(s Strategy) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.strategy = s
+	}
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+// RoundRobin selects the hosts in turn.
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (r *roundRobin) pick(hosts []*poolHost, key string) *poolHost {
+	n := atomic.AddUint64(&r.next, 1) - 1
+	return hosts[n%uint64(len(hosts))]
+}
+
+type epsilonGreedy struct {
+	epsilon float64
+}
+
+// EpsilonGreedy selects the host with the lowest average write latency,
+// except for a ratio `epsilon` of the entries, such as 0.1, written to a
+// random host to measure the latency of the others.
+func EpsilonGreedy(epsilon float64) Strategy {
+	return epsilonGreedy{epsilon: epsilon}
+}
+
+func (e epsilonGreedy) pick(hosts []*poolHost, key string) *poolHost {
+	if rand.Float64() < e.epsilon {
+		return hosts[rand.Intn(len(hosts))]
+	}
+	best := hosts[0]
+	for _, h := range hosts[1:] {
+		if atomic.LoadInt64(&h.latency) < atomic.LoadInt64(&best.latency) {
+			best = h
+		}
+	}
+	return best
+}
+
+type weighted struct {
+	mu      sync.Mutex
+	weights map[string]int
+	current map[string]int
+}
+
+// Weighted selects the hosts in turn in proportion to their weight in
+// `weights`, by address. The hosts not in `weights` have a weight of 1 and
+// the hosts with a weight of 0 are only selected when the others are down.
+func Weighted(weights map[string]int) Strategy {
+	return &weighted{weights: weights, current: make(map[string]int)}
+}
+
+// pick implements the smooth weighted round-robin of nginx, which
+// interleaves the hosts instead of selecting each one several times in a row.
+func (w *weighted) pick(hosts []*poolHost, key string) *poolHost {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var best *poolHost
+	total := 0
+	for _, h := range hosts {
+		weight, ok := w.weights[h.addr]
+		if !ok {
+			weight = 1
+		} else if weight < 0 {
+			weight = 0
+		}
+		w.current[h.addr] += weight
+		total += weight
+		if best == nil || w.current[h.addr] > w.current[best.addr] {
+			best = h
+		}
+	}
+	w.current[best.addr] -= total
+	return best
+}
+
+type leastOutstanding struct {
+	next uint64
+}
+
+// LeastOutstanding selects the host with the fewest writes in progress,
+// the hosts in turn if several have as few.
+func LeastOutstanding() Strategy {
+	return &leastOutstanding{}
+}
+
+func (l *leastOutstanding) pick(hosts []*poolHost, key string) *poolHost {
+	start := atomic.AddUint64(&l.next, 1) - 1
+	var best *poolHost
+	for i := range hosts {
+		h := hosts[(start+uint64(i))%uint64(len(hosts))]
+		if best == nil || atomic.LoadInt64(&h.outstanding) < atomic.LoadInt64(&best.outstanding) {
+			best = h
+		}
+	}
+	return best
+}
+
+type hashField struct {
+	field string
+}
+
+// HashField selects the host from the value of the field `field` of the
+// entries, so the entries with the same value are written to the same host.
+// Only the entries of the hosts which are added or removed, or go down, are
+// moved to other hosts. The entries without the field are written to the
+// same host.
+func HashField(field string) Strategy {
+	return hashField{field: field}
+}
+
+// pick implements rendezvous hashing: the host with the highest hash of the
+// key and its address is selected.
+func (f hashField) pick(hosts []*poolHost, key string) *poolHost {
+	var best *poolHost
+	var max uint64
+	for _, h := range hosts {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(key))
+		_, _ = hash.Write([]byte(h.addr))
+		if sum := hash.Sum64(); best == nil || sum > max {
+			best, max = h, sum
+		}
+	}
+	return best
+}
+
+type failover struct{}
+
+// Failover selects the first host of the pool until it is down, then the
+// next one, and so on.
+func Failover() Strategy {
+	return failover{}
+}
+
+func (failover) pick(hosts []*poolHost, key string) *poolHost {
+	return hosts[0]
+}
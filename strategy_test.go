@@ -0,0 +1,158 @@
+package logrustash
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testHosts(addrs ...string) []*poolHost {
+	var hosts []*poolHost
+	for _, addr := range addrs {
+		hosts = append(hosts, &poolHost{addr: addr})
+	}
+	return hosts
+}
+
+func picks(s Strategy, hosts []*poolHost, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[s.pick(hosts, fmt.Sprint(i)).addr]++
+	}
+	return counts
+}
+
+func TestRoundRobin(t *testing.T) {
+	counts := picks(RoundRobin(), testHosts("ls1:5000", "ls2:5000", "ls3:5000"), 9)
+	for addr, n := range counts {
+		if n != 3 {
+			t.Errorf("expected %s to be picked 3 times but got %d", addr, n)
+		}
+	}
+}
+
+func TestEpsilonGreedy(t *testing.T) {
+	hosts := testHosts("ls1:5000", "ls2:5000")
+	hosts[0].observe(10 * time.Millisecond)
+	hosts[1].observe(time.Millisecond)
+
+	if counts := picks(EpsilonGreedy(0), hosts, 10); counts["ls2:5000"] != 10 {
+		t.Errorf("expected the fastest host to be picked: %v", counts)
+	}
+	if counts := picks(EpsilonGreedy(1), hosts, 1000); counts["ls1:5000"] == 0 {
+		t.Errorf("expected the slowest host to be explored: %v", counts)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	hosts := testHosts("ls1:5000", "ls2:5000", "ls3:5000")
+	s := Weighted(map[string]int{"ls1:5000": 3, "ls3:5000": 0})
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		picked = append(picked, s.pick(hosts, "").addr)
+	}
+	expected := []string{"ls1:5000", "ls1:5000", "ls2:5000", "ls1:5000"}
+	for i := range expected {
+		if picked[i] != expected[i] {
+			t.Errorf("expected to see '%v' in '%v'", expected, picked)
+			break
+		}
+	}
+
+	if h := s.pick(hosts[2:], ""); h != hosts[2] {
+		t.Errorf("expected the host with a weight of 0 to be picked alone: %v", h)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	hosts := testHosts("ls1:5000", "ls2:5000", "ls3:5000")
+	atomic.StoreInt64(&hosts[0].outstanding, 2)
+	atomic.StoreInt64(&hosts[2].outstanding, 1)
+
+	if counts := picks(LeastOutstanding(), hosts, 3); counts["ls2:5000"] != 3 {
+		t.Errorf("expected the host with the fewest writes to be picked: %v", counts)
+	}
+
+	atomic.StoreInt64(&hosts[0].outstanding, 0)
+	atomic.StoreInt64(&hosts[2].outstanding, 0)
+	if counts := picks(LeastOutstanding(), hosts, 3); len(counts) != 3 {
+		t.Errorf("expected the hosts to be picked in turn: %v", counts)
+	}
+}
+
+func TestHashField(t *testing.T) {
+	hosts := testHosts("ls1:5000", "ls2:5000", "ls3:5000")
+	s := HashField("tenant")
+
+	picked := make(map[string]*poolHost)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		picked[key] = s.pick(hosts, key)
+		if s.pick(hosts, key) != picked[key] {
+			t.Fatalf("expected the same host for the key %s", key)
+		}
+	}
+
+	// only the keys of the host removed move
+	for key, h := range picked {
+		if h == hosts[1] {
+			continue
+		}
+		if s.pick([]*poolHost{hosts[0], hosts[2]}, key) != h {
+			t.Errorf("expected the key %s to stay on %s", key, h.addr)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	s := newHostSet([]string{"ls1:5000", "ls2:5000", "ls3:5000"})
+	s.strategy = Failover()
+
+	if h := s.pick(""); h.addr != "ls1:5000" {
+		t.Errorf("expected the first host but got %s", h.addr)
+	}
+	s.hosts[0].setDown(errors.New("down"))
+	if h := s.pick(""); h.addr != "ls2:5000" {
+		t.Errorf("expected the second host but got %s", h.addr)
+	}
+	s.hosts[0].setDown(nil)
+	if h := s.pick(""); h.addr != "ls1:5000" {
+		t.Errorf("expected the first host once restored but got %s", h.addr)
+	}
+}
+
+func TestPoolHashField(t *testing.T) {
+	first := listen(t, false)
+	defer first.Close()
+	second := listen(t, false)
+	defer second.Close()
+
+	h := New(nil, simpleFmter{})
+	err := h.UsePool([]string{first.Addr().String(), second.Addr().String()}, 0, 2, PoolStrategy(HashField("tenant")))
+	if err != nil {
+		t.Fatalf("expected UsePool to not return error: %s", err)
+	}
+	defer h.writer.(*logstashPool).Close()
+
+	for i := 0; i < 10; i++ {
+		if err = h.Fire(logrus.WithField("tenant", "acme")); err != nil {
+			t.Fatalf("expected Fire to not return error: %s", err)
+		}
+	}
+
+	written := 0
+	for _, s := range h.Stats().Hosts {
+		if s.Written != 0 && s.Written != 10 {
+			t.Errorf("expected the entries of a tenant to be written to the same host: %+v", s)
+		}
+		written += int(s.Written)
+	}
+	if written != 10 {
+		t.Errorf("expected 10 entries to be written but got %d", written)
+	}
+}
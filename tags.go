@@ -0,0 +1,77 @@
+package logrustash
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TagsKey is the field holding the Logstash tags.
+	TagsKey = "tags"
+	// MetadataKey is the field holding the Logstash metadata.
+	// Logstash makes it available to the pipeline but does not index it.
+	MetadataKey = "@metadata"
+)
+
+// WithTags returns a new entry with `tags` added to the tags of the entry `e`.
+//
+// Tags given in the formatter fields are merged with the entry tags
+// and sent as a JSON array without duplicates.
+func WithTags(e *logrus.Entry, tags ...string) *logrus.Entry {
+	return e.WithField(TagsKey, mergeTags(e.Data[TagsKey], tags))
+}
+
+// WithMetadata returns a new entry with `md` added to the metadata of the entry `e`.
+//
+// Metadata given in the formatter fields is merged with the entry metadata,
+// the entry values taking precedence.
+func WithMetadata(e *logrus.Entry, md map[string]interface{}) *logrus.Entry {
+	return e.WithField(MetadataKey, mergeMetadata(e.Data[MetadataKey], md))
+}
+
+// mergeLogstashFields merges the tags and metadata of `fields` and `data` into `ne`.
+func mergeLogstashFields(ne *logrus.Entry, fields, data logrus.Fields) {
+	if _, ok := ne.Data[TagsKey]; ok {
+		ne.Data[TagsKey] = mergeTags(fields[TagsKey], data[TagsKey])
+	}
+	if _, ok := ne.Data[MetadataKey]; ok {
+		ne.Data[MetadataKey] = mergeMetadata(fields[MetadataKey], data[MetadataKey])
+	}
+}
+
+// mergeTags returns the tags in `values` in order and without duplicates.
+// Each value can be a tag or a slice of tags.
+func mergeTags(values ...interface{}) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		for _, tag := range toKeywords(v) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// mergeMetadata returns a new map with the keys of all `values`,
+// later values taking precedence.
+func mergeMetadata(values ...interface{}) map[string]interface{} {
+	md := make(map[string]interface{})
+	for _, v := range values {
+		var m map[string]interface{}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			m = t
+		case logrus.Fields:
+			m = t
+		}
+		for k, val := range m {
+			md[k] = val
+		}
+	}
+	return md
+}
@@ -0,0 +1,70 @@
+package logrustash
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithTags(t *testing.T) {
+	e := logrus.NewEntry(logrus.New()).WithField(TagsKey, "a")
+
+	ne := WithTags(WithTags(e, "b", "a"), "c")
+
+	expected := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(ne.Data[TagsKey], expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, ne.Data[TagsKey])
+	}
+	if e.Data[TagsKey] != "a" {
+		t.Errorf("expected entry to not be changed: %#v", e.Data)
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	e := logrus.NewEntry(logrus.New())
+
+	ne := WithMetadata(WithMetadata(e, map[string]interface{}{"index": "a", "id": 1}), map[string]interface{}{"index": "b"})
+
+	expected := map[string]interface{}{"index": "b", "id": 1}
+	if !reflect.DeepEqual(ne.Data[MetadataKey], expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, ne.Data[MetadataKey])
+	}
+}
+
+func TestFormatMergesTagsAndMetadata(t *testing.T) {
+	f := DefaultFormatter(logrus.Fields{
+		TagsKey:     []string{"app", "prod"},
+		MetadataKey: logrus.Fields{"index": "logs"},
+	})
+
+	e := WithMetadata(WithTags(logrus.NewEntry(logrus.New()), "prod", "billing"), map[string]interface{}{"pipeline": "p1"})
+
+	res, err := f.Format(e)
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	expected := []string{
+		`"tags":["app","prod","billing"]`,
+		`"@metadata":{"index":"logs","pipeline":"p1"}`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(string(res), exp) {
+			t.Errorf("expected to have '%s' in '%s'", exp, string(res))
+		}
+	}
+}
+
+func TestFormatWithoutTags(t *testing.T) {
+	f := DefaultFormatter(logrus.Fields{})
+
+	res, err := f.Format(&logrus.Entry{Data: logrus.Fields{}})
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+	if strings.Contains(string(res), TagsKey) || strings.Contains(string(res), MetadataKey) {
+		t.Errorf("expected no tags or metadata in '%s'", string(res))
+	}
+}
@@ -0,0 +1,135 @@
+package logrustash
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Precision is the precision of the Logstash timestamps.
+type Precision int
+
+// Supported timestamp precisions.
+const (
+	PrecisionSecond Precision = iota
+	PrecisionMillisecond
+	PrecisionMicrosecond
+	PrecisionNanosecond
+)
+
+// eventCreatedKey is the field holding the time the entry was formatted to be sent.
+const eventCreatedKey = "event.created"
+
+// layout returns the time layout of the precision.
+func (p Precision) layout() string {
+	switch p {
+	case PrecisionMillisecond:
+		return "2006-01-02T15:04:05.000Z07:00"
+	case PrecisionMicrosecond:
+		return "2006-01-02T15:04:05.000000Z07:00"
+	case PrecisionNanosecond:
+		return "2006-01-02T15:04:05.000000000Z07:00"
+	}
+	return time.RFC3339
+}
+
+// FormatterOption configures a LogstashFormatter created by `DefaultFormatter`.
+type FormatterOption func(*LogstashFormatter)
+
+// FormatUTC converts the timestamps to UTC.
+func FormatUTC() FormatterOption {
+	return func(f *LogstashFormatter) {
+		f.UTC = true
+	}
+}
+
+// FormatPrecision sets the precision of the timestamps.
+func FormatPrecision(p Precision) FormatterOption {
+	return func(f *LogstashFormatter) {
+		f.Precision = p
+	}
+}
+
+// FormatEpochMillis sends the timestamps as milliseconds since the Unix epoch.
+func FormatEpochMillis() FormatterOption {
+	return func(f *LogstashFormatter) {
+		f.EpochMillis = true
+	}
+}
+
+// FormatEventCreated adds an "event.created" field set to the time the entry is formatted,
+// that is the time it is sent by the hook.
+func FormatEventCreated() FormatterOption {
+	return func(f *LogstashFormatter) {
+		f.EventCreated = true
+	}
+}
+
+// formatTime returns `t` as configured by the timestamp options.
+func (f LogstashFormatter) formatTime(t time.Time) interface{} {
+	if f.UTC {
+		t = t.UTC()
+	}
+	if f.EpochMillis {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+	return t.Format(f.Precision.layout())
+}
+
+// timeFormatter applies the timestamp options to the entry `e` and returns
+// the formatter to use for it.
+//
+// Precision and EpochMillis are only supported by the JSON, MessagePack and
+// CBOR formatters, other formatters are returned unchanged.
+func (f LogstashFormatter) timeFormatter(e *logrus.Entry) logrus.Formatter {
+	if f.UTC {
+		e.Time = e.Time.UTC()
+	}
+	if f.EventCreated {
+		e.Data[eventCreatedKey] = f.formatTime(time.Now())
+	}
+	if f.Precision == PrecisionSecond && !f.EpochMillis {
+		return f.Formatter
+	}
+
+	switch t := f.Formatter.(type) {
+	case *logrus.JSONFormatter:
+		nf := *t
+		nf.TimestampFormat, nf.DisableTimestamp, nf.FieldMap = f.timestamp(e, t.DisableTimestamp, t.FieldMap)
+		return &nf
+	case *MessagePackFormatter:
+		nf := *t
+		nf.TimestampFormat, nf.DisableTimestamp, nf.FieldMap = f.timestamp(e, t.DisableTimestamp, t.FieldMap)
+		return &nf
+	case *CBORFormatter:
+		nf := *t
+		nf.TimestampFormat, nf.DisableTimestamp, nf.FieldMap = f.timestamp(e, t.DisableTimestamp, t.FieldMap)
+		return &nf
+	}
+	return f.Formatter
+}
+
+// timestamp returns the timestamp format, whether the timestamp is disabled and
+// the field map of a formatter configured with `disabled` and `fm`.
+//
+// Formatters can only write a string timestamp so, for epoch milliseconds, the
+// timestamp is disabled and set in the entry data instead. The time key is then
+// remapped so that the formatter does not prefix it as a clashing field.
+func (f LogstashFormatter) timestamp(e *logrus.Entry, disabled bool, fm logrus.FieldMap) (string, bool, logrus.FieldMap) {
+	if !f.EpochMillis || disabled {
+		return f.Precision.layout(), disabled, fm
+	}
+
+	timeKey := logrus.FieldKeyTime
+	nfm := logrus.FieldMap{}
+	for k, v := range fm {
+		if k == logrus.FieldKeyTime {
+			timeKey = v
+			continue
+		}
+		nfm[k] = v
+	}
+	nfm[logrus.FieldKeyTime] = "fields." + timeKey
+	e.Data[timeKey] = f.formatTime(e.Time)
+	return "", true, nfm
+}
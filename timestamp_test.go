@@ -0,0 +1,67 @@
+package logrustash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDefaultFormatterTimestampOptions(t *testing.T) {
+	ts := time.Date(2017, 8, 25, 16, 38, 22, 123456789, time.FixedZone("CEST", 2*3600))
+
+	testData := []struct {
+		opts     []FormatterOption
+		expected string
+	}{
+		{nil, `"@timestamp":"2017-08-25T16:38:22+02:00"`},
+		{[]FormatterOption{FormatUTC()}, `"@timestamp":"2017-08-25T14:38:22Z"`},
+		{[]FormatterOption{FormatUTC(), FormatPrecision(PrecisionMillisecond)}, `"@timestamp":"2017-08-25T14:38:22.123Z"`},
+		{[]FormatterOption{FormatPrecision(PrecisionMicrosecond)}, `"@timestamp":"2017-08-25T16:38:22.123456+02:00"`},
+		{[]FormatterOption{FormatUTC(), FormatPrecision(PrecisionNanosecond)}, `"@timestamp":"2017-08-25T14:38:22.123456789Z"`},
+		{[]FormatterOption{FormatEpochMillis()}, `"@timestamp":1503671902123`},
+	}
+
+	for _, test := range testData {
+		f := DefaultFormatter(logrus.Fields{}, test.opts...)
+
+		res, err := f.Format(&logrus.Entry{Time: ts, Data: logrus.Fields{}})
+		if err != nil {
+			t.Errorf("expected Format to not return error: %s", err)
+		}
+		if !strings.Contains(string(res), test.expected) {
+			t.Errorf("expected to have '%s' in '%s'", test.expected, string(res))
+		}
+		if strings.Contains(string(res), "fields.") {
+			t.Errorf("expected no prefixed fields in '%s'", string(res))
+		}
+	}
+}
+
+func TestDefaultFormatterEventCreated(t *testing.T) {
+	f := DefaultFormatter(logrus.Fields{}, FormatUTC(), FormatEventCreated())
+
+	res, err := f.Format(&logrus.Entry{Time: time.Now().Add(-time.Hour), Data: logrus.Fields{}})
+	if err != nil {
+		t.Errorf("expected Format to not return error: %s", err)
+	}
+
+	expected := fmt.Sprintf(`"event.created":"%s`, time.Now().UTC().Format("2006-01-02T15"))
+	if !strings.Contains(string(res), expected) {
+		t.Errorf("expected to have '%s' in '%s'", expected, string(res))
+	}
+}
+
+func TestTimestampOptionsIgnoredByTextFormatter(t *testing.T) {
+	f := LogstashFormatter{
+		Formatter:   &logrus.TextFormatter{DisableColors: true},
+		Precision:   PrecisionMillisecond,
+		EpochMillis: true,
+	}
+
+	if fmter := f.timeFormatter(&logrus.Entry{Data: logrus.Fields{}}); fmter != f.Formatter {
+		t.Errorf("expected formatter to not be changed: %#v", fmter)
+	}
+}
@@ -0,0 +1,160 @@
+package logrustash
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewFromURL returns a new Hook configured from a URL of the form:
+//
+// transport://host1:port1[,host2:port2...][?param=value&...]
+//
+// The transport is one of "tcp", "udp" or "tcp+tls" (also "tls").
+// The supported parameters are:
+//
+// pool=initial,max: send through a connection pool of the given capacities, required with several hosts;
+// async=true|buffer: send entries asynchronously, through a buffer processed in background if "buffer";
+// bufsize=N: size of the async buffer;
+// timeout=D: duration of time before writing a message timesout, e.g. "2s";
+// connect_timeout=D: duration of time a connection to a host may take, 3s by default;
+// keep_alive=D: period of the TCP keep-alives, disabled if negative;
+// levels=L: minimum level of the entries sent, e.g. "warn", or comma separated levels, e.g. "error,info";
+// type=T: Logstash "type" field;
+// tls_ca=F, tls_cert=F, tls_key=F: PEM files of the certificates verifying the hosts and of the client certificate;
+// tls_server_name=N: host name verified in place of the host names of the hosts.
+//
+// For example:
+//
+// hook, err := logrustash.NewFromURL("tcp+tls://ls1:5000,ls2:5000?pool=5,10&async=buffer&bufsize=10000&timeout=2s&levels=warn")
+func NewFromURL(rawurl string) (*Hook, error) {
+	cfg, err := parseURL(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logstash URL %q: %s", rawurl, err)
+	}
+	return NewFromConfig(cfg)
+}
+
+// parseURL returns the configuration given by the URL `rawurl`, see `NewFromURL`.
+func parseURL(rawurl string) (Config, error) {
+	var cfg Config
+	i := strings.Index(rawurl, "://")
+	if i < 0 {
+		return cfg, fmt.Errorf("missing transport")
+	}
+	cfg.Transport = rawurl[:i]
+	if cfg.Transport == "tcp+tls" {
+		cfg.Transport = "tls"
+	}
+
+	rest, query := rawurl[i+3:], ""
+	if j := strings.Index(rest, "?"); j >= 0 {
+		rest, query = rest[:j], rest[j+1:]
+	}
+	cfg.Hosts = strings.Split(strings.TrimSuffix(rest, "/"), ",")
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return cfg, err
+	}
+	for k, v := range params {
+		if err = cfg.setParam(k, v[len(v)-1]); err != nil {
+			return cfg, fmt.Errorf("invalid %s %q: %s", k, v[len(v)-1], err)
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+func (c *Config) setParam(key, value string) error {
+	switch key {
+	case "pool":
+		return c.setPool(value)
+	case "async":
+		switch value {
+		case "true", "buffer":
+			c.Async = value
+		case "false":
+			c.Async = ""
+		default:
+			return fmt.Errorf("expected true, false or buffer")
+		}
+	case "bufsize":
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return err
+		}
+		c.BufferSize = uint(n)
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.Timeout = Duration(d)
+	case "connect_timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.ConnectTimeout = Duration(d)
+	case "keep_alive":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		c.KeepAlive = Duration(d)
+	case "levels":
+		c.Levels = strings.Split(value, ",")
+		_, err := parseLevels(c.Levels)
+		return err
+	case "type":
+		c.Formatter.Fields = map[string]interface{}{"type": value}
+	case "tls_ca":
+		c.tlsSettings().CA = value
+	case "tls_cert":
+		c.tlsSettings().Cert = value
+	case "tls_key":
+		c.tlsSettings().Key = value
+	case "tls_server_name":
+		c.tlsSettings().ServerName = value
+	default:
+		return fmt.Errorf("unknown parameter")
+	}
+	return nil
+}
+
+// tlsSettings returns the TLS configuration of `c`, created if nil.
+func (c *Config) tlsSettings() *TLSConfig {
+	if c.TLS == nil {
+		c.TLS = &TLSConfig{}
+	}
+	return c.TLS
+}
+
+// parseLevels returns the levels named in `names`. A single level stands for
+// itself and all the levels above it.
+func parseLevels(names []string) ([]logrus.Level, error) {
+	var levels []logrus.Level
+	for _, name := range names {
+		l, err := logrus.ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	if len(levels) != 1 {
+		return levels, nil
+	}
+
+	lowest := levels[0]
+	levels = levels[:0]
+	for _, l := range logrus.AllLevels {
+		if l <= lowest {
+			levels = append(levels, l)
+		}
+	}
+	return levels, nil
+}
@@ -0,0 +1,122 @@
+package logrustash
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseURL(t *testing.T) {
+	cfg, err := parseURL("tcp+tls://ls1:5000,ls2:5000?pool=5,10&async=buffer&bufsize=10000&timeout=2s&connect_timeout=1s&keep_alive=30s&levels=warn&type=app&tls_ca=ca.pem&tls_server_name=logstash")
+	if err != nil {
+		t.Fatalf("expected parseURL to not return error: %s", err)
+	}
+
+	expected := Config{
+		Transport:      "tls",
+		Hosts:          []string{"ls1:5000", "ls2:5000"},
+		TLS:            &TLSConfig{CA: "ca.pem", ServerName: "logstash"},
+		Pool:           &PoolConfig{Initial: 5, Max: 10},
+		Async:          "buffer",
+		BufferSize:     10000,
+		Timeout:        Duration(2 * time.Second),
+		ConnectTimeout: Duration(time.Second),
+		KeepAlive:      Duration(30 * time.Second),
+		Levels:         []string{"warn"},
+		Formatter:      FormatterConfig{Fields: map[string]interface{}{"type": "app"}},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected to see '%+v' in '%+v'", expected, cfg)
+	}
+}
+
+func TestParseURLLevels(t *testing.T) {
+	cfg, err := parseURL("udp://127.0.0.1:5000?levels=error,info")
+	if err != nil {
+		t.Fatalf("expected parseURL to not return error: %s", err)
+	}
+
+	levels, err := parseLevels(cfg.Levels)
+	if err != nil {
+		t.Fatalf("expected parseLevels to not return error: %s", err)
+	}
+	expected := []logrus.Level{logrus.ErrorLevel, logrus.InfoLevel}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, levels)
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := parseLevels([]string{"warn"})
+	if err != nil {
+		t.Fatalf("expected parseLevels to not return error: %s", err)
+	}
+
+	expected := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("expected to see '%v' in '%v'", expected, levels)
+	}
+}
+
+func TestParseURLError(t *testing.T) {
+	testData := []struct {
+		url      string
+		expected string
+	}{
+		{"ls1:5000", "missing transport"},
+		{"http://ls1:5000", "unsupported transport"},
+		{"tcp://ls1", "missing port"},
+		{"tcp://ls1:5000,ls2:5000", "a pool is required"},
+		{"udp://ls1:5000?pool=1,2", "not supported with udp"},
+		{"tcp://ls1:5000?pool=5", "invalid pool"},
+		{"tcp://ls1:5000?async=maybe", "invalid async"},
+		{"tcp://ls1:5000?bufsize=10", "a buffer size requires async buffer"},
+		{"tcp://ls1:5000?timeout=2", "invalid timeout"},
+		{"tcp://ls1:5000?connect_timeout=2", "invalid connect_timeout"},
+		{"tcp://ls1:5000?keep_alive=2", "invalid keep_alive"},
+		{"tcp://ls1:5000?levels=loud", "invalid levels"},
+		{"tcp://ls1:5000?color=blue", "invalid color"},
+	}
+
+	for _, test := range testData {
+		_, err := parseURL(test.url)
+		if err == nil {
+			t.Errorf("expected parseURL of '%s' to return error", test.url)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected to see '%s' in '%s'", test.expected, err.Error())
+		}
+	}
+}
+
+func TestNewFromURL(t *testing.T) {
+	h, err := NewFromURL("tcp://" + address + "?pool=1,2&async=buffer&levels=info")
+	if err != nil {
+		t.Fatalf("expected NewFromURL to not return error: %s", err)
+	}
+	if _, ok := h.writer.(*logstashPool); !ok {
+		t.Errorf("expected hook to use a pool: %#v", h.writer)
+	}
+	if !h.async || h.buf == nil {
+		t.Error("expected hook to be asynchronous with a buffer")
+	}
+	if len(h.Levels()) != 5 {
+		t.Errorf("expected levels up to info but got %v", h.Levels())
+	}
+}
+
+func TestNewFromURLError(t *testing.T) {
+	_, err := NewFromURL("tcp://127.0.0.1:7778")
+	if err == nil {
+		t.Error("expected NewFromURL to return error")
+	}
+
+	_, err = NewFromURL("tcp://")
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid logstash URL") {
+		t.Errorf("expected NewFromURL to return a validation error but got %v", err)
+	}
+}